@@ -0,0 +1,42 @@
+// Command protogen writes the JSON Schema and the TypeScript types of the
+// websocket protocol. It is run by go generate in internal/websockets:
+//
+//	go run ../../cmd/protogen -schema protocol.schema.json -ts ../../../Frontend/src/lib/protocol.ts
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ManogyaDahal/GoType/internal/protogen"
+	"github.com/ManogyaDahal/GoType/internal/websockets"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "write the JSON Schema to this file")
+	tsPath := flag.String("ts", "", "write the TypeScript types to this file")
+	flag.Parse()
+
+	specs := websockets.Messages()
+	outputs := []struct {
+		path     string
+		generate func([]websockets.MessageSpec) ([]byte, error)
+	}{
+		{*schemaPath, protogen.Schema},
+		{*tsPath, protogen.TypeScript},
+	}
+	for _, out := range outputs {
+		if out.path == "" {
+			continue
+		}
+		data, err := out.generate(specs)
+		if err == nil {
+			err = os.WriteFile(out.path, data, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "protogen:", err)
+			os.Exit(1)
+		}
+	}
+}
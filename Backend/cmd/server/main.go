@@ -5,6 +5,7 @@ import (
 
 	"github.com/ManogyaDahal/GoType/internal/logger"
 	"github.com/ManogyaDahal/GoType/internal/routes"
+	"github.com/ManogyaDahal/GoType/internal/storage"
 	"github.com/ManogyaDahal/GoType/internal/websockets"
 	"github.com/joho/godotenv"
 )
@@ -24,9 +25,17 @@ func main() {
 
 	logger.Logger.Info("Server starting", "port", port, "env", os.Getenv("ENV"))
 
+	// opening the persistent store (in-memory when DATA_FILE is unset)
+	store, err := storage.Open(os.Getenv("DATA_FILE"))
+	if err != nil {
+		logger.Logger.Error("Failed to open storage", "error", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
 	// creating single hub manager
-	hubManager = websockets.NewHubManager()
+	hubManager = websockets.NewHubManager(store)
 
-	router := routes.SetupRouters(hubManager)
+	router := routes.SetupRouters(hubManager, store)
 	router.Run(":" + port)
 }
@@ -0,0 +1,330 @@
+// Package analytics turns the keystroke telemetry of races into typing
+// analytics: per-character error rates, bigram latencies, consistency and
+// the keys a player is slowest on. Telemetry is optional, a client only
+// sends it when the player enabled it.
+package analytics
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ManogyaDahal/GoType/internal/round"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// Telemetry limits
+const (
+	MaxBatchSize     = 100  // events in one keystrokes message
+	MaxEventsPerRace = 5000 // events kept per player and race
+	MaxKeyLength     = 4    // runes, longer keys are dropped
+
+	// Events later than this after the start are dropped: the longest
+	// round (15 minutes) with some slack
+	MaxEventMs = 20 * 60 * 1000
+
+	// Gaps longer than this are pauses, not typing, and are not timed
+	MaxLatencyMs = 2000
+
+	// Consistency is measured over one second buckets, a race needs this
+	// many buckets to be measured
+	bucketMs              = 1000
+	MinConsistencyBuckets = 3
+)
+
+// Keystroke is one key press of a player. T is the time in milliseconds
+// since the start of the race.
+type Keystroke struct {
+	Key      string `json:"k"`  // key pressed
+	Expected string `json:"e"`  // character the text expected
+	T        int64  `json:"t"`  // ms since the race start
+	Correct  bool   `json:"ok"` // the key matched the expected character
+}
+
+// Batch is the content of a keystrokes message
+type Batch struct {
+	Events []Keystroke `json:"events"`
+}
+
+// Valid tells if an event can be recorded
+func (k Keystroke) Valid() bool {
+	if k.T < 0 || k.T > MaxEventMs || k.Key == "" || k.Expected == "" {
+		return false
+	}
+	return utf8.RuneCountInString(k.Key) <= MaxKeyLength &&
+		utf8.RuneCountInString(k.Expected) <= MaxKeyLength
+}
+
+// Sample is the analysis of the keystrokes of a single race
+type Sample struct {
+	Keystrokes  int
+	Keys        map[string]storage.KeyStat
+	Bigrams     map[string]storage.BigramStat
+	Measured    bool    // enough keystrokes to measure consistency
+	Consistency float64 // 0-100, 100 means a perfectly even pace
+	Variance    float64 // variance of the per-second WPM
+}
+
+// Analyze computes the sample of a race. Events must be in typing order.
+func Analyze(events []Keystroke) Sample {
+	s := Sample{
+		Keystrokes: len(events),
+		Keys:       make(map[string]storage.KeyStat),
+		Bigrams:    make(map[string]storage.BigramStat),
+	}
+
+	var buckets []float64
+	for i, ev := range events {
+		stat := s.Keys[ev.Expected]
+		stat.Presses++
+		if !ev.Correct {
+			stat.Errors++
+		}
+
+		if i > 0 {
+			prev := events[i-1]
+			gap := ev.T - prev.T
+			if gap >= 0 && gap <= MaxLatencyMs {
+				stat.Timed++
+				stat.LatencyMs += gap
+				// A bigram is timed only when both keys were right,
+				// correcting a typo is not the speed of a key pair
+				if ev.Correct && prev.Correct {
+					bigram := s.Bigrams[prev.Expected+ev.Expected]
+					bigram.Count++
+					bigram.LatencyMs += gap
+					s.Bigrams[prev.Expected+ev.Expected] = bigram
+				}
+			}
+		}
+		s.Keys[ev.Expected] = stat
+
+		if ev.Correct && ev.T >= 0 && ev.T <= MaxEventMs {
+			b := int(ev.T / bucketMs)
+			for len(buckets) <= b {
+				buckets = append(buckets, 0)
+			}
+			buckets[b]++
+		}
+	}
+
+	// The first bucket holds the reaction to the start, drop it
+	if len(buckets) > 1 {
+		buckets = buckets[1:]
+	}
+	if len(buckets) >= MinConsistencyBuckets {
+		for i := range buckets {
+			// characters per second → words per minute (5 chars a word)
+			buckets[i] = buckets[i] * 60 / 5
+		}
+		mean, variance := meanVariance(buckets)
+		s.Measured = true
+		s.Variance = round.Tenth(variance)
+		if mean > 0 {
+			s.Consistency = round.Tenth(math.Max(0, 100*(1-math.Sqrt(variance)/mean)))
+		}
+	}
+	return s
+}
+
+// Merge adds the sample of a race to the stats of a user
+func Merge(stats *storage.TypingStats, s Sample, at time.Time) {
+	if stats.Keys == nil {
+		stats.Keys = make(map[string]storage.KeyStat)
+	}
+	if stats.Bigrams == nil {
+		stats.Bigrams = make(map[string]storage.BigramStat)
+	}
+
+	stats.Races++
+	stats.Keystrokes += s.Keystrokes
+	for key, k := range s.Keys {
+		total := stats.Keys[key]
+		total.Presses += k.Presses
+		total.Errors += k.Errors
+		total.Timed += k.Timed
+		total.LatencyMs += k.LatencyMs
+		stats.Keys[key] = total
+	}
+	for key, b := range s.Bigrams {
+		total := stats.Bigrams[key]
+		total.Count += b.Count
+		total.LatencyMs += b.LatencyMs
+		stats.Bigrams[key] = total
+	}
+	if s.Measured {
+		stats.ConsistencyRaces++
+		stats.ConsistencySum += s.Consistency
+		stats.VarianceSum += s.Variance
+	}
+	stats.UpdatedAt = at
+}
+
+// Recorder stores the telemetry of finished races. Hubs finish races
+// concurrently, the recorder serializes the read-modify-write of stats.
+type Recorder struct {
+	store storage.Store
+	mu    sync.Mutex
+}
+
+// NewRecorder returns a recorder writing to store
+func NewRecorder(store storage.Store) *Recorder {
+	return &Recorder{store: store}
+}
+
+// Record merges the keystrokes of one race into the stats of the user
+func (r *Recorder) Record(userID string, events []Keystroke, at time.Time) error {
+	if len(events) == 0 {
+		return nil
+	}
+	sample := Analyze(events)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, err := r.store.GetTypingStats(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		stats = storage.TypingStats{UserID: userID}
+	} else if err != nil {
+		return err
+	}
+	// The stored maps may be shared with readers, never modify them in place
+	stats = clone(stats)
+	Merge(&stats, sample, at)
+	return r.store.SaveTypingStats(stats)
+}
+
+func clone(stats storage.TypingStats) storage.TypingStats {
+	keys := make(map[string]storage.KeyStat, len(stats.Keys))
+	for k, v := range stats.Keys {
+		keys[k] = v
+	}
+	bigrams := make(map[string]storage.BigramStat, len(stats.Bigrams))
+	for k, v := range stats.Bigrams {
+		bigrams[k] = v
+	}
+	stats.Keys, stats.Bigrams = keys, bigrams
+	return stats
+}
+
+// KeyReport is the analysis of one character
+type KeyReport struct {
+	Key          string  `json:"key"`
+	Presses      int     `json:"presses"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"` // percent
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// BigramReport is the analysis of one pair of characters
+type BigramReport struct {
+	Bigram       string  `json:"bigram"`
+	Count        int     `json:"count"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// Report is what the analytics API returns
+type Report struct {
+	Races          int            `json:"races"`
+	Keystrokes     int            `json:"keystrokes"`
+	Consistency    float64        `json:"consistency"`  // average over the measured races
+	WPMVariance    float64        `json:"wpm_variance"` // average over the measured races
+	Keys           []KeyReport    `json:"keys"`         // every character, by error rate
+	SlowestKeys    []KeyReport    `json:"slowest_keys"`
+	SlowestBigrams []BigramReport `json:"slowest_bigrams"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// Minimum samples before a key or bigram appears in the slowest lists, a
+// single slow press says nothing
+const (
+	MinKeySamples    = 5
+	MinBigramSamples = 3
+)
+
+// BuildReport turns the stats of a user into a report with the top slowest
+// keys and bigrams
+func BuildReport(stats storage.TypingStats, top int) Report {
+	report := Report{
+		Races:          stats.Races,
+		Keystrokes:     stats.Keystrokes,
+		Keys:           make([]KeyReport, 0, len(stats.Keys)),
+		SlowestKeys:    []KeyReport{},
+		SlowestBigrams: []BigramReport{},
+		UpdatedAt:      stats.UpdatedAt,
+	}
+	if stats.ConsistencyRaces > 0 {
+		report.Consistency = round.Tenth(stats.ConsistencySum / float64(stats.ConsistencyRaces))
+		report.WPMVariance = round.Tenth(stats.VarianceSum / float64(stats.ConsistencyRaces))
+	}
+
+	for key, k := range stats.Keys {
+		kr := KeyReport{Key: key, Presses: k.Presses, Errors: k.Errors}
+		if k.Presses > 0 {
+			kr.ErrorRate = round.Tenth(100 * float64(k.Errors) / float64(k.Presses))
+		}
+		if k.Timed > 0 {
+			kr.AvgLatencyMs = round.Tenth(float64(k.LatencyMs) / float64(k.Timed))
+		}
+		report.Keys = append(report.Keys, kr)
+		if k.Timed >= MinKeySamples {
+			report.SlowestKeys = append(report.SlowestKeys, kr)
+		}
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		a, b := report.Keys[i], report.Keys[j]
+		if a.ErrorRate != b.ErrorRate {
+			return a.ErrorRate > b.ErrorRate
+		}
+		return a.Key < b.Key
+	})
+	sort.Slice(report.SlowestKeys, func(i, j int) bool {
+		a, b := report.SlowestKeys[i], report.SlowestKeys[j]
+		if a.AvgLatencyMs != b.AvgLatencyMs {
+			return a.AvgLatencyMs > b.AvgLatencyMs
+		}
+		return a.Key < b.Key
+	})
+	report.SlowestKeys = head(report.SlowestKeys, top)
+
+	for bigram, b := range stats.Bigrams {
+		if b.Count < MinBigramSamples {
+			continue
+		}
+		report.SlowestBigrams = append(report.SlowestBigrams, BigramReport{
+			Bigram:       bigram,
+			Count:        b.Count,
+			AvgLatencyMs: round.Tenth(float64(b.LatencyMs) / float64(b.Count)),
+		})
+	}
+	sort.Slice(report.SlowestBigrams, func(i, j int) bool {
+		a, b := report.SlowestBigrams[i], report.SlowestBigrams[j]
+		if a.AvgLatencyMs != b.AvgLatencyMs {
+			return a.AvgLatencyMs > b.AvgLatencyMs
+		}
+		return a.Bigram < b.Bigram
+	})
+	report.SlowestBigrams = head(report.SlowestBigrams, top)
+	return report
+}
+
+func head[T any](items []T, n int) []T {
+	if n > 0 && n < len(items) {
+		return items[:n]
+	}
+	return items
+}
+
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values))
+}
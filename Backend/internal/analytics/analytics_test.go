@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"testing"
+)
+
+func TestKeystrokeValid(t *testing.T) {
+	tests := []struct {
+		name string
+		k    Keystroke
+		want bool
+	}{
+		{"valid", Keystroke{Key: "a", Expected: "a", T: 120}, true},
+		{"at the start", Keystroke{Key: "a", Expected: "a", T: 0}, true},
+		{"at the limit", Keystroke{Key: "a", Expected: "a", T: MaxEventMs}, true},
+		{"negative time", Keystroke{Key: "a", Expected: "a", T: -1}, false},
+		{"after the limit", Keystroke{Key: "a", Expected: "a", T: MaxEventMs + 1}, false},
+		{"huge time", Keystroke{Key: "a", Expected: "a", T: 1e15}, false},
+		{"no key", Keystroke{Expected: "a", T: 10}, false},
+		{"no expected", Keystroke{Key: "a", T: 10}, false},
+		{"long key", Keystroke{Key: "Shift", Expected: "a", T: 10}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.k.Valid(); got != tt.want {
+			t.Errorf("%s: Valid() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A huge T must not size the consistency buckets, they would take all
+// the memory of the server
+func TestAnalyzeHugeTime(t *testing.T) {
+	events := []Keystroke{
+		{Key: "a", Expected: "a", T: 100, Correct: true},
+		{Key: "b", Expected: "b", T: 1e15, Correct: true},
+		{Key: "c", Expected: "c", T: 1 << 62, Correct: true},
+	}
+	allocs := testing.AllocsPerRun(1, func() {
+		s := Analyze(events)
+		if s.Keystrokes != len(events) {
+			t.Errorf("Keystrokes = %d, want %d", s.Keystrokes, len(events))
+		}
+	})
+	if allocs > 100 {
+		t.Errorf("Analyze made %v allocations", allocs)
+	}
+}
+
+func TestAnalyzeConsistency(t *testing.T) {
+	// 10 correct keys per second for 5 seconds, an even pace
+	var events []Keystroke
+	for ms := int64(0); ms < 5000; ms += 100 {
+		events = append(events, Keystroke{Key: "a", Expected: "a", T: ms, Correct: true})
+	}
+	s := Analyze(events)
+	if !s.Measured {
+		t.Fatal("consistency not measured")
+	}
+	if s.Variance != 0 || s.Consistency != 100 {
+		t.Errorf("variance %v, consistency %v, want 0 and 100", s.Variance, s.Consistency)
+	}
+}
@@ -0,0 +1,50 @@
+package analytics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ManogyaDahal/GoType/internal/auth"
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/query"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Size of the slowest keys / bigrams lists
+const (
+	DefaultTop = 10
+	MaxTop     = 50
+)
+
+// Handler serves GET /api/me/analytics, the typing analytics of the logged
+// in user. Optional query: ?top=10 (size of the slowest lists).
+func Handler(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _, ok := auth.SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
+
+		top, err := query.Limit(c, "top", DefaultTop, MaxTop)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid top"})
+			return
+		}
+
+		stats, err := store.GetTypingStats(userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			// No telemetry recorded yet, an empty report
+			stats = storage.TypingStats{UserID: userID}
+		} else if err != nil {
+			logger.Logger.Error("[Analytics] Failed to load typing stats",
+				"user", userID,
+				"error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+			return
+		}
+
+		c.JSON(http.StatusOK, BuildReport(stats, top))
+	}
+}
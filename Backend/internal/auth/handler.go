@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/rating"
+	"github.com/ManogyaDahal/GoType/internal/storage"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -41,8 +44,9 @@ func LoginHandler(cfg *oauth2.Config) gin.HandlerFunc {
 	}
 }
 
-// Callback Handler handles
-func CallbackHandler(cfg *oauth2.Config) gin.HandlerFunc {
+// Callback Handler handles the redirect back from google, fetches the user
+// info, records the user in the store and sets the session
+func CallbackHandler(cfg *oauth2.Config, store storage.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		stateInUrl := c.Query("state")
 
@@ -90,8 +94,23 @@ func CallbackHandler(cfg *oauth2.Config) gin.HandlerFunc {
 			return
 		}
 
+		// recording the user, a failing store must not block the login
+		now := time.Now()
+		if err := store.UpsertUser(storage.User{
+			ID:        userInfo.ID,
+			Name:      userInfo.Name,
+			Email:     userInfo.Email,
+			Picture:   userInfo.Picture,
+			CreatedAt: now,
+			LastSeen:  now,
+		}); err != nil {
+			logger.Logger.Error("[STORAGE] Failed to save user",
+				"error", err)
+		}
+
 		// setting values in session
 		session := sessions.Default(c)
+		session.Set("UserID", userInfo.ID)
 		session.Set("Name", userInfo.Name)
 		session.Set("Email", userInfo.Email)
 		session.Set("VerifiedEmail", userInfo.VerifiedEmail)
@@ -122,23 +141,48 @@ func LogoutHandler(c *gin.Context) {
 	c.Redirect(http.StatusFound, frontendURL()+"/")
 }
 
-// WhoAmI returns the current user and a short-lived WebSocket auth token.
-// The ws_token lets the frontend authenticate WebSocket connections that go
-// directly to Render, bypassing the Vercel proxy where the session lives.
-func WhoAmI(c *gin.Context) {
-	session := sessions.Default(c)
-	name := session.Get("Name")
+// WhoAmI returns the current user, its skill rating and a short-lived
+// WebSocket auth token. The ws_token lets the frontend authenticate WebSocket
+// connections that go directly to Render, bypassing the Vercel proxy where
+// the session lives.
+func WhoAmI(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, name, ok := SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
 
-	if name == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
-		return
-	}
+		wsToken := GenerateWSToken(userID, name)
+		picture, _ := sessions.Default(c).Get("Picture").(string)
 
-	nameStr := name.(string)
-	wsToken := GenerateWSToken(nameStr)
+		userRating, err := rating.Lookup(store, userID)
+		if err != nil {
+			logger.Logger.Error("[AUTH] Failed to load rating",
+				"user", userID,
+				"error", err)
+		}
 
-	c.JSON(http.StatusOK, gin.H{
-		"name":     nameStr,
-		"ws_token": wsToken,
-	})
+		c.JSON(http.StatusOK, gin.H{
+			"id":       userID,
+			"name":     name,
+			"picture":  picture,
+			"ws_token": wsToken,
+			"rating":   userRating.Rating,
+			"races":    userRating.Races,
+		})
+	}
+}
+
+// SessionUser returns the user ID and display name stored in the session.
+// Sessions created before user IDs were stored have no "UserID" and are
+// treated as logged out, so the user logs in again and gets one.
+func SessionUser(c *gin.Context) (userID string, name string, ok bool) {
+	session := sessions.Default(c)
+	userID, _ = session.Get("UserID").(string)
+	name, _ = session.Get("Name").(string)
+	if userID == "" {
+		return "", "", false
+	}
+	return userID, name, true
 }
@@ -8,7 +8,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -97,46 +96,54 @@ func ValidateState(state string) bool {
 }
 
 // GenerateWSToken creates a short-lived HMAC-signed token for WebSocket auth.
-// Format: urlencoded(name).timestamp.signature
+// Format: base64(userID).base64(name).timestamp.signature
+// The user ID is the stable identity, the name is only carried for display.
+// Both are base64url encoded so a "." in a name can't break the format.
 // The frontend fetches this from /api/whoamI (through the Vercel proxy where
 // the session is valid) and passes it as ?token= in the WebSocket URL, which
 // connects directly to Render — bypassing the proxy entirely.
-func GenerateWSToken(name string) string {
-	encodedName := url.QueryEscape(name)
+func GenerateWSToken(userID, name string) string {
+	encodedID := base64.RawURLEncoding.EncodeToString([]byte(userID))
+	encodedName := base64.RawURLEncoding.EncodeToString([]byte(name))
 	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	payload := encodedName + "." + timestamp
+	payload := encodedID + "." + encodedName + "." + timestamp
 	signature := signState(payload)
 	return payload + "." + signature
 }
 
 // ValidateWSToken verifies the HMAC-signed WebSocket token and returns the
-// embedded user name. Tokens are valid for 2 hours to accommodate long sessions.
-func ValidateWSToken(token string) (string, bool) {
-	parts := strings.SplitN(token, ".", 3)
-	if len(parts) != 3 {
-		return "", false
+// embedded user ID and name. Tokens are valid for 2 hours to accommodate
+// long sessions.
+func ValidateWSToken(token string) (userID string, name string, ok bool) {
+	parts := strings.SplitN(token, ".", 4)
+	if len(parts) != 4 {
+		return "", "", false
 	}
 
-	payload := parts[0] + "." + parts[1]
-	signature := parts[2]
+	payload := parts[0] + "." + parts[1] + "." + parts[2]
+	signature := parts[3]
 
 	// Verify HMAC signature — prevents forgery
 	expected := signState(payload)
 	if !hmac.Equal([]byte(signature), []byte(expected)) {
-		return "", false
+		return "", "", false
 	}
 
 	// Verify token is within 2 hours
 	var ts int64
-	fmt.Sscanf(parts[1], "%d", &ts)
+	fmt.Sscanf(parts[2], "%d", &ts)
 	elapsed := time.Since(time.Unix(ts, 0))
 	if elapsed > 2*time.Hour || elapsed < -1*time.Minute {
-		return "", false
+		return "", "", false
 	}
 
-	name, err := url.QueryUnescape(parts[0])
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	decodedName, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return "", false
+		return "", "", false
 	}
-	return name, true
+	return string(id), string(decodedName), true
 }
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedWSToken builds a token the way GenerateWSToken does, issued at
+func signedWSToken(userID, name string, at time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(name)) + "." +
+		fmt.Sprintf("%d", at.Unix())
+	return payload + "." + signState(payload)
+}
+
+func TestWSToken(t *testing.T) {
+	t.Setenv("SESSION_SECRET", "test-secret")
+
+	valid := GenerateWSToken("1234567890", "John Smith")
+	parts := strings.Split(valid, ".")
+	forgedID := base64.RawURLEncoding.EncodeToString([]byte("0987654321"))
+
+	tests := []struct {
+		name     string
+		token    string
+		wantID   string
+		wantName string
+		wantOK   bool
+	}{
+		{"valid", valid, "1234567890", "John Smith", true},
+		{"dots in the name", GenerateWSToken("42", "J. R. R. Tolkien"), "42", "J. R. R. Tolkien", true},
+		{"unicode name", GenerateWSToken("42", "मनोज"), "42", "मनोज", true},
+		{"same name, other user", GenerateWSToken("43", "John Smith"), "43", "John Smith", true},
+		{"an hour old", signedWSToken("42", "n", time.Now().Add(-time.Hour)), "42", "n", true},
+		{"expired", signedWSToken("42", "n", time.Now().Add(-3*time.Hour)), "", "", false},
+		{"issued in the future", signedWSToken("42", "n", time.Now().Add(time.Hour)), "", "", false},
+		{"forged user ID", forgedID + "." + parts[1] + "." + parts[2] + "." + parts[3], "", "", false},
+		{"bad signature", parts[0] + "." + parts[1] + "." + parts[2] + ".00", "", "", false},
+		{"missing signature", parts[0] + "." + parts[1] + "." + parts[2], "", "", false},
+		{"empty", "", "", "", false},
+	}
+	for _, tt := range tests {
+		id, name, ok := ValidateWSToken(tt.token)
+		if id != tt.wantID || name != tt.wantName || ok != tt.wantOK {
+			t.Errorf("%s: ValidateWSToken = %q, %q, %v, want %q, %q, %v", tt.name, id, name, ok, tt.wantID, tt.wantName, tt.wantOK)
+		}
+	}
+
+	// Signed with another secret
+	t.Setenv("SESSION_SECRET", "other-secret")
+	if _, _, ok := ValidateWSToken(valid); ok {
+		t.Error("token accepted with another secret")
+	}
+}
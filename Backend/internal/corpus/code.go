@@ -0,0 +1,131 @@
+package corpus
+
+import (
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed code
+var codeFiles embed.FS
+
+// CodeAny picks a snippet of any programming language
+const CodeAny = "any"
+
+// Snippet is a piece of source code to race on. Its text keeps newlines
+// and indentation, tabs are expanded to TabWidth spaces.
+type Snippet struct {
+	ID       string `json:"id"`       // <language>/<name>
+	Language string `json:"language"` // programming language
+	Text     string `json:"text"`
+	Lines    int    `json:"lines"`
+}
+
+// TabWidth is the number of spaces a tab of a snippet is expanded to
+const TabWidth = 4
+
+// Code holds the snippets by programming language
+type Code struct {
+	snippets map[string][]Snippet
+}
+
+// DefaultCode is the set of snippets embedded in the binary
+var DefaultCode = mustLoadCode()
+
+func mustLoadCode() *Code {
+	c, err := LoadCode()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadCode reads the embedded snippets, every code/<language>/<name>.txt
+// file is one snippet
+func LoadCode() (*Code, error) {
+	c := &Code{snippets: make(map[string][]Snippet)}
+	err := fs.WalkDir(codeFiles, "code", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := codeFiles.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		lang := path.Base(path.Dir(p))
+		s := Snippet{
+			ID:       lang + "/" + strings.TrimSuffix(path.Base(p), ".txt"),
+			Language: lang,
+			Text:     cleanCode(string(data)),
+		}
+		if s.Text == "" {
+			return fmt.Errorf("%s is empty", p)
+		}
+		s.Lines = strings.Count(s.Text, "\n") + 1
+		c.snippets[lang] = append(c.snippets[lang], s)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("corpus: %w", err)
+	}
+	for _, snippets := range c.snippets {
+		sort.Slice(snippets, func(i, j int) bool { return snippets[i].ID < snippets[j].ID })
+	}
+	return c, nil
+}
+
+// cleanCode normalizes a snippet: LF line endings, tabs expanded, no
+// trailing whitespace on lines and no blank lines around the code
+func cleanCode(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", strings.Repeat(" ", TabWidth))
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// Languages returns the programming languages that have snippets, sorted
+func (c *Code) Languages() []string {
+	out := make([]string, 0, len(c.snippets))
+	for lang := range c.snippets {
+		out = append(out, lang)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Snippets returns the snippets of a programming language, of every
+// language for CodeAny
+func (c *Code) Snippets(lang string) []Snippet {
+	if lang != CodeAny {
+		return append([]Snippet(nil), c.snippets[lang]...)
+	}
+	out := make([]Snippet, 0)
+	for _, l := range c.Languages() {
+		out = append(out, c.snippets[l]...)
+	}
+	return out
+}
+
+// Has tells if there are snippets of the programming language
+func (c *Code) Has(lang string) bool {
+	return len(c.Snippets(lang)) > 0
+}
+
+// Pick returns the snippet seed selects among the snippets of a
+// programming language. The same seed always picks the same snippet.
+func (c *Code) Pick(lang, seed string) (Snippet, bool) {
+	snippets := c.Snippets(lang)
+	if len(snippets) == 0 {
+		return Snippet{}, false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	return snippets[h.Sum32()%uint32(len(snippets))], true
+}
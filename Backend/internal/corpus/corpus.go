@@ -0,0 +1,183 @@
+// Package corpus holds the real-world texts rooms can race on: quotes and
+// passages with their source, embedded in the binary from data/<lang>.json,
+// source code snippets embedded from code/<language>/, and the validation
+// of custom texts submitted by hosts.
+package corpus
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed data/*.json
+var files embed.FS
+
+// Length is the length category of a quote
+type Length string
+
+const (
+	LengthAny    Length = "any"
+	LengthShort  Length = "short"  // fewer than MediumChars characters
+	LengthMedium Length = "medium" // fewer than LongChars characters
+	LengthLong   Length = "long"
+)
+
+// Length category boundaries, in characters
+const (
+	MediumChars = 100
+	LongChars   = 250
+)
+
+// IsValidLength tells if the length category exists
+func IsValidLength(l Length) bool {
+	switch l {
+	case LengthAny, LengthShort, LengthMedium, LengthLong:
+		return true
+	default:
+		return false
+	}
+}
+
+// LengthOf returns the category of a text
+func LengthOf(text string) Length {
+	switch n := utf8.RuneCountInString(text); {
+	case n < MediumChars:
+		return LengthShort
+	case n < LongChars:
+		return LengthMedium
+	default:
+		return LengthLong
+	}
+}
+
+// Quote is one text of the corpus with its attribution
+type Quote struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Source   string `json:"source"` // work the text is taken from
+	Author   string `json:"author"`
+	Language string `json:"language"`
+	Length   Length `json:"length"`
+}
+
+// Corpus is a set of quotes by language
+type Corpus struct {
+	quotes map[string][]Quote
+}
+
+// Default is the corpus embedded in the binary
+var Default = mustLoad()
+
+func mustLoad() *Corpus {
+	c, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Load reads the embedded corpus. Every data/<lang>.json file holds the
+// quotes of one language.
+func Load() (*Corpus, error) {
+	entries, err := files.ReadDir("data")
+	if err != nil {
+		return nil, fmt.Errorf("corpus: %w", err)
+	}
+
+	c := &Corpus{quotes: make(map[string][]Quote)}
+	for _, e := range entries {
+		lang := strings.TrimSuffix(e.Name(), ".json")
+		data, err := files.ReadFile(path.Join("data", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("corpus: %w", err)
+		}
+		var quotes []Quote
+		if err := json.Unmarshal(data, &quotes); err != nil {
+			return nil, fmt.Errorf("corpus: decode %s: %w", e.Name(), err)
+		}
+		for i := range quotes {
+			q := &quotes[i]
+			q.Text = strings.Join(strings.Fields(q.Text), " ")
+			if q.ID == "" || q.Text == "" {
+				return nil, fmt.Errorf("corpus: %s: quote %d has no id or text", e.Name(), i)
+			}
+			q.Language = lang
+			q.Length = LengthOf(q.Text)
+		}
+		sort.Slice(quotes, func(i, j int) bool { return quotes[i].ID < quotes[j].ID })
+		c.quotes[lang] = quotes
+	}
+	return c, nil
+}
+
+// Quotes returns the quotes of a language in a length category
+func (c *Corpus) Quotes(lang string, length Length) []Quote {
+	out := make([]Quote, 0)
+	for _, q := range c.quotes[lang] {
+		if length == LengthAny || q.Length == length {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// Has tells if the corpus has a quote of the language in the category
+func (c *Corpus) Has(lang string, length Length) bool {
+	return len(c.Quotes(lang, length)) > 0
+}
+
+// Pick returns the quote seed selects among the quotes of a language in a
+// length category. The same seed always picks the same quote.
+func (c *Corpus) Pick(lang string, length Length, seed string) (Quote, bool) {
+	quotes := c.Quotes(lang, length)
+	if len(quotes) == 0 {
+		return Quote{}, false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	return quotes[h.Sum32()%uint32(len(quotes))], true
+}
+
+// Custom text limits, in characters
+const (
+	MinCustomChars = 20
+	MaxCustomChars = 2000
+)
+
+// Errors returned by CleanCustom
+var (
+	ErrCustomLength = fmt.Errorf("custom text must be between %d and %d characters", MinCustomChars, MaxCustomChars)
+	ErrCustomChars  = errors.New("custom text contains characters that can't be typed")
+)
+
+// CleanCustom validates a custom text submitted by a host and returns it
+// ready to race: whitespace runs, newlines included, become single spaces.
+// Only printable letters, marks, digits, punctuation and symbols are
+// allowed.
+func CleanCustom(text string) (string, error) {
+	if !utf8.ValidString(text) {
+		return "", ErrCustomChars
+	}
+	cleaned := strings.Join(strings.Fields(text), " ")
+	n := utf8.RuneCountInString(cleaned)
+	if n < MinCustomChars || n > MaxCustomChars {
+		return "", ErrCustomLength
+	}
+	for _, r := range cleaned {
+		if r == ' ' {
+			continue
+		}
+		if !unicode.IsPrint(r) || !unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S) {
+			return "", ErrCustomChars
+		}
+	}
+	return cleaned, nil
+}
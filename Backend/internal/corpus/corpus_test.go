@@ -0,0 +1,45 @@
+package corpus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCleanCustom(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr error
+	}{
+		{"kept as is", "the quick brown fox jumps", "the quick brown fox jumps", nil},
+		{"whitespace runs", "the  quick\tbrown   fox jumps", "the quick brown fox jumps", nil},
+		{"newlines", "the quick\nbrown\r\n\nfox jumps", "the quick brown fox jumps", nil},
+		{"trimmed", "  \n the quick brown fox jumps \t\n", "the quick brown fox jumps", nil},
+		{"unicode spaces", "the\u00a0quick\u2003brown fox jumps", "the quick brown fox jumps", nil},
+		{"punctuation and symbols", "Wait... 3 + 4 = 7, right?!", "Wait... 3 + 4 = 7, right?!", nil},
+		{"accents and other scripts", "mañana él canción नेपाल", "mañana él canción नेपाल", nil},
+		{"empty", "", "", ErrCustomLength},
+		{"only whitespace", " \n\t \r\n ", "", ErrCustomLength},
+		{"too short", "short text", "", ErrCustomLength},
+		{"short once cleaned", "a          b          c", "", ErrCustomLength},
+		{"shortest", strings.Repeat("a", MinCustomChars), strings.Repeat("a", MinCustomChars), nil},
+		{"longest", strings.Repeat("a", MaxCustomChars), strings.Repeat("a", MaxCustomChars), nil},
+		{"too long", strings.Repeat("a", MaxCustomChars+1), "", ErrCustomLength},
+		{"limit counts runes", strings.Repeat("ñ", MaxCustomChars), strings.Repeat("ñ", MaxCustomChars), nil},
+		{"control character", "the quick brown\x00fox jumps", "", ErrCustomChars},
+		{"zero width space", "the quick brown\u200bfox jumps", "", ErrCustomChars},
+		{"invalid utf-8", "the quick brown \xff fox jumps", "", ErrCustomChars},
+	}
+	for _, tt := range tests {
+		got, err := CleanCustom(tt.text)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: CleanCustom(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
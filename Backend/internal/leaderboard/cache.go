@@ -0,0 +1,61 @@
+package leaderboard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// CacheTTL is how long a built board is served before it is built again
+const CacheTTL = 30 * time.Second
+
+// Cache keeps the boards built recently. A leaderboard is polled by every
+// client showing it, with the cache its races are loaded and ranked once
+// per TTL instead of on every request. Safe for concurrent use.
+type Cache struct {
+	store  storage.Store
+	ttl    time.Duration
+	mu     sync.Mutex
+	boards map[Query]cachedBoard
+}
+
+type cachedBoard struct {
+	board   Board
+	builtAt time.Time
+}
+
+// NewCache returns a cache building its boards from store
+func NewCache(store storage.Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl, boards: make(map[Query]cachedBoard)}
+}
+
+// Board returns the board of q built at most ttl before now. A new race
+// shows on the board once the cached one expired.
+func (c *Cache) Board(q Query, now time.Time) (Board, error) {
+	if q.Window == "" {
+		q.Window = WindowAll
+	}
+	c.mu.Lock()
+	cached, ok := c.boards[q]
+	c.mu.Unlock()
+	if ok && now.Sub(cached.builtAt) < c.ttl {
+		return cached.board, nil
+	}
+
+	// Built unlocked, a slow store doesn't hold up the cached boards
+	board, err := Build(c.store, q, now)
+	if err != nil {
+		return Board{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, b := range c.boards {
+		if now.Sub(b.builtAt) >= c.ttl {
+			delete(c.boards, key)
+		}
+	}
+	c.boards[q] = cachedBoard{board: board, builtAt: now}
+	return board, nil
+}
@@ -0,0 +1,74 @@
+package leaderboard
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/auth"
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/query"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves GET /api/leaderboard.
+// Query: ?mode=words&word_count=25&language=en&window=daily|weekly|all
+// &limit=50&offset=0, or ?mode=timed&duration=60 for timed races. "me" is
+// the standing of the logged in user (null when unranked). Boards are
+// cached for CacheTTL.
+func Handler(store storage.Store) gin.HandlerFunc {
+	boards := NewCache(store, CacheTTL)
+	return func(c *gin.Context) {
+		q := Query{
+			Mode:     c.Query("mode"),
+			Language: c.Query("language"),
+			Window:   Window(c.DefaultQuery("window", string(WindowAll))),
+		}
+		if !IsValidWindow(q.Window) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window"})
+			return
+		}
+
+		var err error
+		if q.WordCount, err = query.Int(c, "word_count", 0); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid word_count"})
+			return
+		}
+		if q.Duration, err = query.Int(c, "duration", 0); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+			return
+		}
+		limit, err := query.Limit(c, "limit", DefaultLimit, MaxLimit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		offset, err := query.Int(c, "offset", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+
+		board, err := boards.Board(q, time.Now())
+		if err != nil {
+			logger.Logger.Error("[Leaderboard] Failed to build leaderboard", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load leaderboard"})
+			return
+		}
+
+		var me *Entry
+		if userID, _, ok := auth.SessionUser(c); ok {
+			if e, found := board.Find(userID); found {
+				me = &e
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"window":  q.Window,
+			"total":   len(board.Entries),
+			"offset":  offset,
+			"entries": board.Page(offset, limit),
+			"me":      me,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+func TestHandlerMe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	store.SaveRace(storage.Race{
+		Mode:       "words",
+		Verified:   true,
+		FinishedAt: time.Now(),
+		Results:    []storage.Result{finished("a", 80, 95), finished("b", 60, 95), finished("c", 40, 95)},
+	})
+
+	// The logged in user is taken from the X-User header
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("secret"))), func(c *gin.Context) {
+		if id := c.GetHeader("X-User"); id != "" {
+			sessions.Default(c).Set("UserID", id)
+		}
+	})
+	router.GET("/leaderboard", Handler(store))
+
+	tests := []struct {
+		name    string
+		query   string
+		user    string
+		status  int
+		entries int
+		me      int // rank, 0 for null
+	}{
+		{"anonymous", "", "", http.StatusOK, 3, 0},
+		{"ranked", "", "b", http.StatusOK, 3, 2},
+		{"ranked off the page", "limit=1", "c", http.StatusOK, 1, 3},
+		{"unranked", "", "nobody", http.StatusOK, 3, 0},
+		{"ranked elsewhere", "mode=timed", "a", http.StatusOK, 0, 0},
+		{"invalid window", "window=monthly", "a", http.StatusBadRequest, 0, 0},
+		{"invalid limit", "limit=x", "a", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/leaderboard?"+tt.query, nil)
+		req.Header.Set("X-User", tt.user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Total   int     `json:"total"`
+			Entries []Entry `json:"entries"`
+			Me      *Entry  `json:"me"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		me := 0
+		if body.Me != nil {
+			me = body.Me.Rank
+			if body.Me.UserID != tt.user {
+				t.Errorf("%s: me is %s, want %s", tt.name, body.Me.UserID, tt.user)
+			}
+		}
+		if len(body.Entries) != tt.entries || me != tt.me {
+			t.Errorf("%s: %d entries and me ranked %d, want %d and %d", tt.name, len(body.Entries), me, tt.entries, tt.me)
+		}
+	}
+}
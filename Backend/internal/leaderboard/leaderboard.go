@@ -0,0 +1,184 @@
+// Package leaderboard ranks players by their best verified race. Only races
+// whose results were computed by the server race engine count, a client can
+// never put itself on the board. Unranked races (custom texts, ghost races)
+// don't count either.
+package leaderboard
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/round"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// Window is the period a leaderboard covers
+type Window string
+
+const (
+	WindowDaily  Window = "daily"  // the last 24 hours
+	WindowWeekly Window = "weekly" // the last 7 days
+	WindowAll    Window = "all"    // all-time
+)
+
+// Since returns the start of the window, zero for all-time
+func (w Window) Since(now time.Time) time.Time {
+	switch w {
+	case WindowDaily:
+		return now.Add(-24 * time.Hour)
+	case WindowWeekly:
+		return now.Add(-7 * 24 * time.Hour)
+	default:
+		return time.Time{}
+	}
+}
+
+// IsValidWindow tells if the window is supported
+func IsValidWindow(w Window) bool {
+	switch w {
+	case WindowDaily, WindowWeekly, WindowAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pagination limits
+const (
+	DefaultLimit = 50
+	MaxLimit     = 100
+)
+
+// Query selects the races a leaderboard is built from. Zero values mean
+// "no filter".
+type Query struct {
+	Mode      string
+	Language  string
+	WordCount int
+	Duration  int // seconds, timed races
+	Window    Window
+}
+
+// Entry is the standing of one player
+type Entry struct {
+	Rank     int       `json:"rank"`
+	UserID   string    `json:"user_id"`
+	Name     string    `json:"name"`
+	BestWPM  float64   `json:"best_wpm"`
+	Accuracy float64   `json:"accuracy"` // accuracy of the best race
+	AvgWPM   float64   `json:"avg_wpm"`
+	Races    int       `json:"races"`
+	BestAt   time.Time `json:"best_at"`
+}
+
+// Board is a ranked leaderboard
+type Board struct {
+	Entries []Entry
+}
+
+// Build loads the races matching q and ranks their finishers
+func Build(store storage.Store, q Query, now time.Time) (Board, error) {
+	if q.Window == "" {
+		q.Window = WindowAll
+	}
+	if !IsValidWindow(q.Window) {
+		return Board{}, fmt.Errorf("invalid window: %s", q.Window)
+	}
+
+	races, err := store.ListRaces(storage.RaceFilter{
+		Mode:         q.Mode,
+		Language:     q.Language,
+		WordCount:    q.WordCount,
+		Duration:     q.Duration,
+		VerifiedOnly: true,
+		RankedOnly:   true,
+		Since:        q.Window.Since(now),
+	})
+	if err != nil {
+		return Board{}, err
+	}
+	return Board{Entries: Rank(races)}, nil
+}
+
+// Rank aggregates the finished results of races per player and orders the
+// players by best WPM, then accuracy, then who got there first. Results
+// flagged by the anti-cheat are left out. Races must be sorted newest first
+// (as ListRaces returns them), so the name of a player is the most recent one.
+func Rank(races []storage.Race) []Entry {
+	byUser := make(map[string]*Entry)
+	totals := make(map[string]float64)
+	for _, race := range races {
+		for _, r := range race.Results {
+			if !r.Finished || r.Flagged || r.UserID == "" {
+				continue
+			}
+			e, ok := byUser[r.UserID]
+			if !ok {
+				e = &Entry{UserID: r.UserID, Name: r.Name}
+				byUser[r.UserID] = e
+			}
+			e.Races++
+			totals[r.UserID] += r.WPM
+			if r.WPM > e.BestWPM || (r.WPM == e.BestWPM && !race.FinishedAt.After(e.BestAt)) {
+				e.BestWPM = r.WPM
+				e.Accuracy = r.Accuracy
+				e.BestAt = race.FinishedAt
+			}
+		}
+	}
+
+	entries := make([]Entry, 0, len(byUser))
+	for id, e := range byUser {
+		e.AvgWPM = round.Tenth(totals[id] / float64(e.Races))
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.BestWPM != b.BestWPM {
+			return a.BestWPM > b.BestWPM
+		}
+		if a.Accuracy != b.Accuracy {
+			return a.Accuracy > b.Accuracy
+		}
+		if !a.BestAt.Equal(b.BestAt) {
+			return a.BestAt.Before(b.BestAt)
+		}
+		return a.UserID < b.UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// Page returns limit entries starting at offset
+func (b Board) Page(offset, limit int) []Entry {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(b.Entries) {
+		offset = len(b.Entries)
+	}
+	end := offset + limit
+	if end > len(b.Entries) {
+		end = len(b.Entries)
+	}
+	return b.Entries[offset:end]
+}
+
+// Find returns the entry of the user
+func (b Board) Find(userID string) (Entry, bool) {
+	for _, e := range b.Entries {
+		if e.UserID == userID {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,214 @@
+package leaderboard
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+var at = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// raceAt returns a verified race finished minutes after at
+func raceAt(minutes int, results ...storage.Result) storage.Race {
+	return storage.Race{
+		ID:         fmt.Sprint(minutes),
+		Mode:       "words",
+		Language:   "en",
+		WordCount:  25,
+		Verified:   true,
+		FinishedAt: at.Add(time.Duration(minutes) * time.Minute),
+		Results:    results,
+	}
+}
+
+func finished(userID string, wpm, accuracy float64) storage.Result {
+	return storage.Result{UserID: userID, Name: userID, WPM: wpm, Accuracy: accuracy, Finished: true}
+}
+
+// ranking formats entries as user:best/avg(races) in rank order
+func ranking(entries []Entry) string {
+	s := ""
+	for i, e := range entries {
+		if e.Rank != i+1 {
+			return fmt.Sprintf("entry %d ranked %d", i, e.Rank)
+		}
+		s += fmt.Sprintf("%s:%v/%v(%d) ", e.UserID, e.BestWPM, e.AvgWPM, e.Races)
+	}
+	return s
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		races []storage.Race // newest first
+		want  string
+	}{
+		{
+			name:  "by best WPM",
+			races: []storage.Race{raceAt(1, finished("a", 60, 95), finished("b", 80, 90))},
+			want:  "b:80/80(1) a:60/60(1) ",
+		},
+		{
+			name: "best and average over the races",
+			races: []storage.Race{
+				raceAt(2, finished("a", 70, 95)),
+				raceAt(1, finished("a", 50, 95), finished("b", 65, 90)),
+			},
+			want: "a:70/60(2) b:65/65(1) ",
+		},
+		{
+			name:  "equal WPM by accuracy",
+			races: []storage.Race{raceAt(1, finished("a", 60, 90), finished("b", 60, 98))},
+			want:  "b:60/60(1) a:60/60(1) ",
+		},
+		{
+			name: "equal WPM and accuracy, first to get there",
+			races: []storage.Race{
+				raceAt(2, finished("a", 60, 95)),
+				raceAt(1, finished("b", 60, 95)),
+			},
+			want: "b:60/60(1) a:60/60(1) ",
+		},
+		{
+			name:  "complete tie by user ID",
+			races: []storage.Race{raceAt(1, finished("b", 60, 95), finished("a", 60, 95))},
+			want:  "a:60/60(1) b:60/60(1) ",
+		},
+		{
+			name: "DNF, flagged and anonymous results left out",
+			races: []storage.Race{raceAt(1,
+				finished("a", 60, 95),
+				storage.Result{UserID: "dnf", WPM: 90},
+				storage.Result{UserID: "bot", WPM: 300, Finished: true, Flagged: true},
+				storage.Result{WPM: 100, Finished: true},
+			)},
+			want: "a:60/60(1) ",
+		},
+		{
+			name: "average rounded to a tenth",
+			races: []storage.Race{
+				raceAt(3, finished("a", 50, 95)),
+				raceAt(2, finished("a", 50, 95)),
+				raceAt(1, finished("a", 51, 95)),
+			},
+			want: "a:51/50.3(3) ",
+		},
+		{name: "no races", want: ""},
+	}
+	for _, tt := range tests {
+		if got := ranking(Rank(tt.races)); got != tt.want {
+			t.Errorf("%s: ranking %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The name is the most recent one, the accuracy that of the best race
+	entries := Rank([]storage.Race{
+		raceAt(2, storage.Result{UserID: "a", Name: "New", WPM: 50, Accuracy: 99, Finished: true}),
+		raceAt(1, storage.Result{UserID: "a", Name: "Old", WPM: 70, Accuracy: 91, Finished: true}),
+	})
+	if e := entries[0]; e.Name != "New" || e.Accuracy != 91 || !e.BestAt.Equal(at.Add(time.Minute)) {
+		t.Errorf("entry %+v, want named New with the accuracy and time of the 70 WPM race", e)
+	}
+}
+
+func TestPage(t *testing.T) {
+	board := Board{Entries: make([]Entry, 2*MaxLimit)}
+	for i := range board.Entries {
+		board.Entries[i].Rank = i + 1
+	}
+	tests := []struct {
+		offset, limit int
+		first, n      int // rank of the first entry, number of entries
+	}{
+		{0, 10, 1, 10},
+		{5, 10, 6, 10},
+		{0, 0, 1, DefaultLimit},
+		{0, -1, 1, DefaultLimit},
+		{0, MaxLimit + 1, 1, MaxLimit},
+		{-5, 10, 1, 10},
+		{2*MaxLimit - 3, 10, 2*MaxLimit - 2, 3},
+		{2 * MaxLimit, 10, 0, 0},
+		{1 << 62, MaxLimit, 0, 0},
+	}
+	for _, tt := range tests {
+		page := board.Page(tt.offset, tt.limit)
+		first := 0
+		if len(page) > 0 {
+			first = page[0].Rank
+		}
+		if first != tt.first || len(page) != tt.n {
+			t.Errorf("Page(%d, %d) = %d entries from rank %d, want %d from %d", tt.offset, tt.limit, len(page), first, tt.n, tt.first)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	store := storage.NewMemoryStore()
+	now := at.Add(48 * time.Hour)
+	old := raceAt(0, finished("old", 90, 95))
+	unverified := raceAt(10, finished("client", 200, 100))
+	unverified.Verified = false
+	custom := raceAt(20, finished("custom", 150, 100))
+	custom.Unranked = true
+	timed := raceAt(30, finished("timed", 70, 95))
+	timed.Mode, timed.WordCount, timed.Duration = "timed", 0, 60
+	german := raceAt(40, finished("german", 65, 95))
+	german.Language = "de"
+	recent := raceAt(47*60, finished("recent", 60, 95))
+	for _, r := range []storage.Race{old, unverified, custom, timed, german, recent} {
+		store.SaveRace(r)
+	}
+
+	tests := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"verified and ranked only", Query{}, "old:90/90(1) timed:70/70(1) german:65/65(1) recent:60/60(1) "},
+		{"by mode and word count", Query{Mode: "words", WordCount: 25}, "old:90/90(1) german:65/65(1) recent:60/60(1) "},
+		{"by duration", Query{Mode: "timed", Duration: 60}, "timed:70/70(1) "},
+		{"by language", Query{Language: "de"}, "german:65/65(1) "},
+		{"daily", Query{Window: WindowDaily}, "recent:60/60(1) "},
+		{"weekly", Query{Window: WindowWeekly}, "old:90/90(1) timed:70/70(1) german:65/65(1) recent:60/60(1) "},
+	}
+	for _, tt := range tests {
+		board, err := Build(store, tt.q, now)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ranking(board.Entries); got != tt.want {
+			t.Errorf("%s: ranking %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := Build(store, Query{Window: "monthly"}, now); err == nil {
+		t.Error("board built for an invalid window")
+	}
+}
+
+func TestCache(t *testing.T) {
+	store := storage.NewMemoryStore()
+	store.SaveRace(raceAt(0, finished("a", 60, 95)))
+	boards := NewCache(store, time.Minute)
+	now := at.Add(time.Hour)
+
+	board, err := boards.Board(Query{}, now)
+	if err != nil || len(board.Entries) != 1 {
+		t.Fatalf("board %+v (%v), want a", board, err)
+	}
+
+	store.SaveRace(raceAt(1, finished("b", 80, 95)))
+	if board, _ := boards.Board(Query{Window: WindowAll}, now.Add(59*time.Second)); len(board.Entries) != 1 {
+		t.Errorf("%d entries, want the cached board", len(board.Entries))
+	}
+	if board, _ := boards.Board(Query{Mode: "words"}, now); len(board.Entries) != 2 {
+		t.Errorf("%d entries for another query, want a fresh board", len(board.Entries))
+	}
+	if board, _ := boards.Board(Query{}, now.Add(time.Minute)); len(board.Entries) != 2 {
+		t.Errorf("%d entries once expired, want a rebuilt board", len(board.Entries))
+	}
+	if _, err := boards.Board(Query{Window: "monthly"}, now); err == nil {
+		t.Error("board built for an invalid window")
+	}
+}
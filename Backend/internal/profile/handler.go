@@ -0,0 +1,144 @@
+// Package profile serves the public profile of a user (who they are, how
+// fast they type and how their skill rating evolved) and their race history.
+package profile
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/auth"
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/query"
+	"github.com/ManogyaDahal/GoType/internal/rating"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Page size limits for the rating history and the race history
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// Profile is the public view of a user, the email is never exposed
+type Profile struct {
+	ID            string                 `json:"id"`
+	Name          string                 `json:"name"`
+	Picture       string                 `json:"picture"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Rating        float64                `json:"rating"`
+	RatedRaces    int                    `json:"rated_races"`
+	Stats         Stats                  `json:"stats"`
+	RatingHistory []storage.RatingChange `json:"rating_history"` // newest first
+}
+
+// Handler serves GET /api/users/:id, "me" is the logged in user.
+// Optional query: ?history=20 (number of rating changes, newest first).
+func Handler(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.Param("id")
+		if userID == "me" {
+			id, _, ok := auth.SessionUser(c)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+				return
+			}
+			userID = id
+		}
+
+		limit, err := query.Limit(c, "history", DefaultHistoryLimit, MaxHistoryLimit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history"})
+			return
+		}
+
+		user, err := store.GetUser(userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			internalError(c, "Failed to load user", err)
+			return
+		}
+
+		userRating, err := rating.Lookup(store, userID)
+		if err != nil {
+			internalError(c, "Failed to load rating", err)
+			return
+		}
+
+		races, err := store.ListRaces(storage.RaceFilter{UserID: userID})
+		if err != nil {
+			internalError(c, "Failed to load races", err)
+			return
+		}
+
+		history, err := store.ListRatingHistory(userID, limit)
+		if err != nil {
+			internalError(c, "Failed to load rating history", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, Profile{
+			ID:            user.ID,
+			Name:          user.Name,
+			Picture:       user.Picture,
+			CreatedAt:     user.CreatedAt,
+			Rating:        userRating.Rating,
+			RatedRaces:    userRating.Races,
+			Stats:         ComputeStats(races, userID),
+			RatingHistory: history,
+		})
+	}
+}
+
+// HistoryHandler serves GET /api/me/history, the past races of the logged
+// in user, newest first. Query: ?limit=20&offset=0
+func HistoryHandler(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _, ok := auth.SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
+
+		limit, err := query.Limit(c, "limit", DefaultHistoryLimit, MaxHistoryLimit)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		offset, err := query.Int(c, "offset", 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+
+		// The total is needed for pagination, so load the user's races once
+		// and slice them here
+		races, err := store.ListRaces(storage.RaceFilter{UserID: userID})
+		if err != nil {
+			internalError(c, "Failed to load races", err)
+			return
+		}
+		total := len(races)
+		start := min(offset, total)
+		races = races[start:min(start+limit, total)]
+
+		entries := make([]HistoryEntry, 0, len(races))
+		for _, race := range races {
+			entries = append(entries, NewHistoryEntry(race, userID))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":  total,
+			"offset": offset,
+			"races":  entries,
+		})
+	}
+}
+
+func internalError(c *gin.Context, msg string, err error) {
+	logger.Logger.Error("[Profile] "+msg, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
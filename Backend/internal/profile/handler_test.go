@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+func TestHistoryPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := storage.NewMemoryStore()
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		store.SaveRace(storage.Race{
+			ID:         strconv.Itoa(i),
+			FinishedAt: at.Add(time.Duration(i) * time.Minute),
+			Results:    []storage.Result{{UserID: "u", Finished: true}},
+		})
+	}
+
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("secret"))), func(c *gin.Context) {
+		sessions.Default(c).Set("UserID", "u")
+	})
+	router.GET("/history", HistoryHandler(store))
+
+	tests := []struct {
+		query string
+		races string // IDs, newest first
+	}{
+		{"", "43210"},
+		{"limit=2", "43"},
+		{"limit=2&offset=3", "10"},
+		{"offset=5", ""},
+		{"offset=9223372036854775807", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/history?"+tt.query, nil))
+		var body struct {
+			Total int            `json:"total"`
+			Races []HistoryEntry `json:"races"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%q: status %d, %v", tt.query, w.Code, err)
+			continue
+		}
+		var ids string
+		for _, r := range body.Races {
+			ids += r.RaceID
+		}
+		if body.Total != 5 || ids != tt.races {
+			t.Errorf("%q: races %q of %d, want %q of 5", tt.query, ids, body.Total, tt.races)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package profile
+
+import (
+	"math"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/round"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// Number of latest finished races the "recent" numbers and the trend
+// are computed over
+const RecentRaces = 10
+
+// Stats summarizes the races of a user
+type Stats struct {
+	RacesPlayed    int          `json:"races_played"`
+	RacesFinished  int          `json:"races_finished"`
+	Wins           int          `json:"wins"`
+	BestWPM        float64      `json:"best_wpm"`
+	AvgWPM         float64      `json:"avg_wpm"`
+	RecentWPM      float64      `json:"recent_wpm"` // average over the latest RecentRaces
+	AvgAccuracy    float64      `json:"avg_accuracy"`
+	RecentAccuracy float64      `json:"recent_accuracy"`
+	Trend          []TrendPoint `json:"trend"` // latest finished races, oldest first
+}
+
+// TrendPoint is the WPM and accuracy of one finished race
+type TrendPoint struct {
+	RaceID   string    `json:"race_id"`
+	At       time.Time `json:"at"`
+	WPM      float64   `json:"wpm"`
+	Accuracy float64   `json:"accuracy"`
+}
+
+// HistoryEntry is one past race from the point of view of a user
+type HistoryEntry struct {
+	RaceID       string    `json:"race_id"`
+	RoomID       string    `json:"room_id"`
+	Mode         string    `json:"mode"`
+	Language     string    `json:"language"`
+	WordCount    int       `json:"word_count"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Participants int       `json:"participants"`
+	Place        int       `json:"place"`
+	WPM          float64   `json:"wpm"`
+	Accuracy     float64   `json:"accuracy"`
+	TimeMs       int64     `json:"time_ms"`
+	Finished     bool      `json:"finished"`
+}
+
+// ComputeStats summarizes the races of a user. Races must be sorted
+// newest first, as ListRaces returns them. Averages only count finished
+// races, an abandoned race says nothing about typing speed, and leave out
+// the results flagged by the anti-cheat like the leaderboard does.
+func ComputeStats(races []storage.Race, userID string) Stats {
+	stats := Stats{Trend: []TrendPoint{}}
+	var wpmSum, accSum, recentWPM, recentAcc float64
+	for _, race := range races {
+		res, ok := race.Result(userID)
+		if !ok {
+			continue
+		}
+		stats.RacesPlayed++
+		if !res.Finished || res.Flagged {
+			continue
+		}
+		if res.Place == 1 && len(race.Results) > 1 {
+			stats.Wins++
+		}
+		stats.BestWPM = math.Max(stats.BestWPM, res.WPM)
+		wpmSum += res.WPM
+		accSum += res.Accuracy
+		if stats.RacesFinished < RecentRaces {
+			recentWPM += res.WPM
+			recentAcc += res.Accuracy
+			stats.Trend = append(stats.Trend, TrendPoint{
+				RaceID:   race.ID,
+				At:       race.FinishedAt,
+				WPM:      res.WPM,
+				Accuracy: res.Accuracy,
+			})
+		}
+		stats.RacesFinished++
+	}
+
+	if stats.RacesFinished > 0 {
+		recent := float64(len(stats.Trend))
+		stats.AvgWPM = round.Tenth(wpmSum / float64(stats.RacesFinished))
+		stats.AvgAccuracy = round.Tenth(accSum / float64(stats.RacesFinished))
+		stats.RecentWPM = round.Tenth(recentWPM / recent)
+		stats.RecentAccuracy = round.Tenth(recentAcc / recent)
+	}
+
+	// Newest first was collected, a trend reads oldest first
+	for i, j := 0, len(stats.Trend)-1; i < j; i, j = i+1, j-1 {
+		stats.Trend[i], stats.Trend[j] = stats.Trend[j], stats.Trend[i]
+	}
+	return stats
+}
+
+// NewHistoryEntry returns the race as seen by the user
+func NewHistoryEntry(race storage.Race, userID string) HistoryEntry {
+	res, _ := race.Result(userID)
+	return HistoryEntry{
+		RaceID:       race.ID,
+		RoomID:       race.RoomID,
+		Mode:         race.Mode,
+		Language:     race.Language,
+		WordCount:    race.WordCount,
+		FinishedAt:   race.FinishedAt,
+		Participants: len(race.Results),
+		Place:        res.Place,
+		WPM:          res.WPM,
+		Accuracy:     res.Accuracy,
+		TimeMs:       res.TimeMs,
+		Finished:     res.Finished,
+	}
+}
@@ -0,0 +1,28 @@
+package profile
+
+import (
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+func TestComputeStatsSkipsFlagged(t *testing.T) {
+	races := []storage.Race{
+		{ID: "3", Results: []storage.Result{
+			{UserID: "u", Place: 1, WPM: 250, Accuracy: 100, Finished: true, Flagged: true},
+			{UserID: "o", Place: 2, WPM: 60, Accuracy: 95, Finished: true},
+		}},
+		{ID: "2", Results: []storage.Result{{UserID: "u", Place: 1, WPM: 80, Accuracy: 96, Finished: true}}},
+		{ID: "1", Results: []storage.Result{{UserID: "u", Place: 1, WPM: 70, Accuracy: 94, Finished: true}}},
+	}
+	s := ComputeStats(races, "u")
+	if s.RacesPlayed != 3 || s.RacesFinished != 2 {
+		t.Errorf("played %d, finished %d, want 3 and 2", s.RacesPlayed, s.RacesFinished)
+	}
+	if s.BestWPM != 80 || s.AvgWPM != 75 || s.AvgAccuracy != 95 {
+		t.Errorf("best %v, avg %v, accuracy %v, want 80, 75 and 95", s.BestWPM, s.AvgWPM, s.AvgAccuracy)
+	}
+	if s.Wins != 0 || len(s.Trend) != 2 {
+		t.Errorf("wins %d, trend %d, want 0 and 2", s.Wins, len(s.Trend))
+	}
+}
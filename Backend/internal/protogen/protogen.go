@@ -0,0 +1,148 @@
+// Package protogen generates the machine-readable definition of the
+// websocket protocol from the message specs of the websockets package: a
+// JSON Schema of every message both sides send and the TypeScript types of
+// the frontend. The Go structs stay the source of truth, the generated
+// files are refreshed with go generate (see cmd/protogen).
+//
+// Payload types are read with reflection following the rules of
+// encoding/json: fields are named after their json tag, omitempty fields
+// are optional, embedded structs are flattened and unexported fields are
+// left out. Named structs become definitions of their own, named after the
+// Go type.
+package protogen
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/websockets"
+)
+
+var (
+	timeType    = reflect.TypeFor[time.Time]()
+	rawType     = reflect.TypeFor[json.RawMessage]()
+	messageType = reflect.TypeFor[websockets.Message]()
+)
+
+// field is a JSON field of a struct
+type field struct {
+	Name     string
+	Type     reflect.Type
+	Optional bool
+}
+
+// fieldsOf returns the JSON fields of a struct type in declaration order
+func fieldsOf(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, fieldsOf(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{Name: name, Type: ft, Optional: opts == "omitempty"})
+	}
+	return fields
+}
+
+// isDef tells if a type gets a definition of its own
+func isDef(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Name() != "" && t != timeType
+}
+
+// collector gathers the named struct types reachable from the payloads,
+// in the order they are first met
+type collector struct {
+	types map[string]reflect.Type
+	order []string
+}
+
+func (c *collector) add(t reflect.Type) error {
+	switch {
+	case t == rawType || t == timeType:
+		return nil
+	case t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return c.add(t.Elem())
+	case t.Kind() == reflect.Map:
+		return c.add(t.Elem())
+	case t.Kind() != reflect.Struct:
+		return nil
+	}
+	if isDef(t) {
+		if seen, ok := c.types[t.Name()]; ok {
+			if seen != t {
+				return fmt.Errorf("protogen: %s and %s have the same name", seen.PkgPath(), t.PkgPath())
+			}
+			return nil
+		}
+		c.types[t.Name()] = t
+		c.order = append(c.order, t.Name())
+	}
+	for _, f := range fieldsOf(t) {
+		if err := c.add(f.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentTypes returns the types a content of a spec can hold
+func contentTypes(content any) []reflect.Type {
+	switch v := content.(type) {
+	case nil:
+		return nil
+	case websockets.AnyOf:
+		types := make([]reflect.Type, 0, len(v))
+		for _, alt := range v {
+			types = append(types, reflect.TypeOf(alt))
+		}
+		return types
+	default:
+		return []reflect.Type{reflect.TypeOf(v)}
+	}
+}
+
+// collect returns the definitions used by the specs, sorted as met
+func collect(specs []websockets.MessageSpec) (*collector, error) {
+	c := &collector{types: make(map[string]reflect.Type)}
+	for _, spec := range specs {
+		for _, content := range []any{spec.Client, spec.Server} {
+			for _, t := range contentTypes(content) {
+				if err := c.add(t); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// envelope returns the fields of a message other than its type and content
+func envelope() []field {
+	var fields []field
+	for _, f := range fieldsOf(messageType) {
+		if f.Name != "type" && f.Name != "content" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,172 @@
+package protogen
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/ManogyaDahal/GoType/internal/websockets"
+)
+
+// SchemaID is the $id of the generated schema
+const SchemaID = "urn:gotype:protocol"
+
+// object is a JSON Schema object. encoding/json sorts map keys, so the
+// generated file is stable.
+type object = map[string]any
+
+// Schema returns the JSON Schema of the protocol. ClientMessage and
+// ServerMessage are the messages each side sends, one alternative per
+// message type; payloads are under $defs.
+func Schema(specs []websockets.MessageSpec) ([]byte, error) {
+	c, err := collect(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	defs := object{}
+	for _, name := range c.order {
+		defs[name] = structSchema(c.types[name])
+	}
+
+	env := envelope()
+	envProps := object{}
+	var envRequired []string
+	for _, f := range env {
+		envProps[f.Name] = typeSchema(f.Type)
+		if !f.Optional {
+			envRequired = append(envRequired, f.Name)
+		}
+	}
+	defs["Envelope"] = object{
+		"description": "Fields every message may carry besides type and content",
+		"type":        "object",
+		"properties":  envProps,
+	}
+
+	var client, server []any
+	for _, spec := range specs {
+		if spec.Client != nil {
+			required := []string{"type"}
+			if _, signal := spec.Client.(websockets.Signal); !signal {
+				required = append(required, "content")
+			}
+			client = append(client, messageSchema(spec, spec.Client, required))
+		}
+		if spec.Server != nil {
+			required := append([]string{"type", "content"}, envRequired...)
+			server = append(server, messageSchema(spec, spec.Server, required))
+		}
+	}
+	defs["ClientMessage"] = object{
+		"description": "A message sent by a client",
+		"oneOf":       client,
+	}
+	defs["ServerMessage"] = object{
+		"description": "A message sent by the server",
+		"oneOf":       server,
+	}
+
+	schema := object{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         SchemaID,
+		"title":       "GoType websocket protocol",
+		"description": "Messages exchanged over /ws, generated from the Go types by cmd/protogen. Do not edit.",
+		"version":     websockets.LatestProtocol,
+		"anyOf": []any{
+			object{"$ref": "#/$defs/ClientMessage"},
+			object{"$ref": "#/$defs/ServerMessage"},
+		},
+		"$defs": defs,
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// messageSchema returns the schema of one message type in one direction
+func messageSchema(spec websockets.MessageSpec, content any, required []string) object {
+	return object{
+		"title":       spec.Type,
+		"description": spec.Doc,
+		"$ref":        "#/$defs/Envelope",
+		"properties": object{
+			"type":    object{"const": spec.Type},
+			"content": contentSchema(content),
+		},
+		"required": required,
+	}
+}
+
+// contentSchema returns the schema of the content of a message
+func contentSchema(content any) object {
+	types := contentTypes(content)
+	if _, ok := content.(websockets.AnyOf); !ok {
+		return typeSchema(types[0])
+	}
+	alts := make([]any, 0, len(types))
+	for _, t := range types {
+		alts = append(alts, typeSchema(t))
+	}
+	return object{"oneOf": alts}
+}
+
+// structSchema returns the schema of the fields of a struct
+func structSchema(t reflect.Type) object {
+	props := object{}
+	required := []string{}
+	for _, f := range fieldsOf(t) {
+		props[f.Name] = typeSchema(f.Type)
+		if !f.Optional {
+			required = append(required, f.Name)
+		}
+	}
+	return object{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// typeSchema returns the schema of a value of type t
+func typeSchema(t reflect.Type) object {
+	switch {
+	case t == rawType:
+		return object{}
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case isDef(t):
+		return object{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "contentEncoding": "base64"}
+		}
+		return object{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return object{}
+}
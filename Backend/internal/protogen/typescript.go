@@ -0,0 +1,145 @@
+package protogen
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ManogyaDahal/GoType/internal/websockets"
+)
+
+// TypeScript returns the TypeScript bindings of the protocol: the protocol
+// version, a MessageType constant per message type, an interface per
+// payload and the ClientMessage and ServerMessage unions.
+func TypeScript(specs []websockets.MessageSpec) ([]byte, error) {
+	c, err := collect(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by cmd/protogen from the Go message types. DO NOT EDIT.\n\n")
+	b.WriteString("/** Version of the websocket protocol these types describe, sent as ?protocol= */\n")
+	fmt.Fprintf(&b, "export const PROTOCOL_VERSION = %d;\n\n", websockets.LatestProtocol)
+
+	b.WriteString("/** Type of every message of the protocol */\n")
+	b.WriteString("export const MessageType = {\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "  /** %s */\n  %s: %q,\n", spec.Doc, constName(spec.Type), spec.Type)
+	}
+	b.WriteString("} as const;\n\n")
+	b.WriteString("export type MessageType = (typeof MessageType)[keyof typeof MessageType];\n")
+
+	for _, name := range c.order {
+		fmt.Fprintf(&b, "\nexport interface %s %s\n", name, tsFields(fieldsOf(c.types[name]), ""))
+	}
+
+	b.WriteString("\n/** Fields every message may carry besides type and content */\n")
+	fmt.Fprintf(&b, "export interface Envelope %s\n", tsFields(envelope(), ""))
+
+	b.WriteString("\n/** A message sent by a client, the envelope is filled in by the server */\n")
+	b.WriteString("export type ClientMessage =")
+	for _, spec := range specs {
+		if spec.Client == nil {
+			continue
+		}
+		content := "content: " + tsContent(spec.Client)
+		if _, signal := spec.Client.(websockets.Signal); signal {
+			content = "content?: unknown"
+		}
+		fmt.Fprintf(&b, "\n  | (Partial<Envelope> & { type: %q; %s })", spec.Type, content)
+	}
+	b.WriteString(";\n")
+
+	b.WriteString("\n/** A message sent by the server */\n")
+	b.WriteString("export type ServerMessage =")
+	for _, spec := range specs {
+		if spec.Server == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  | (Envelope & { type: %q; content: %s })", spec.Type, tsContent(spec.Server))
+	}
+	b.WriteString(";\n")
+
+	b.WriteString("\n/** Content of the messages of type T sent by a client */\n")
+	b.WriteString("export type ClientContent<T extends ClientMessage[\"type\"]> = Extract<ClientMessage, { type: T }>[\"content\"];\n")
+	b.WriteString("\n/** Content of the messages of type T sent by the server */\n")
+	b.WriteString("export type ServerContent<T extends ServerMessage[\"type\"]> = Extract<ServerMessage, { type: T }>[\"content\"];\n")
+	return b.Bytes(), nil
+}
+
+// constName returns the MessageType key of a message type,
+// player_progress becomes PlayerProgress
+func constName(messageType string) string {
+	parts := strings.Split(messageType, "_")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// tsContent returns the TypeScript type of the content of a message
+func tsContent(content any) string {
+	types := contentTypes(content)
+	alts := make([]string, 0, len(types))
+	for _, t := range types {
+		alts = append(alts, tsType(t, ""))
+	}
+	return strings.Join(alts, " | ")
+}
+
+// tsFields returns the body of an interface with the fields
+func tsFields(fields []field, indent string) string {
+	if len(fields) == 0 {
+		return "{}"
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, f := range fields {
+		optional := ""
+		if f.Optional {
+			optional = "?"
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, f.Name, optional, tsType(f.Type, indent+"  "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// tsType returns the TypeScript type of a value of type t
+func tsType(t reflect.Type, indent string) string {
+	switch {
+	case t == rawType:
+		return "unknown"
+	case t == timeType:
+		return "string"
+	case isDef(t):
+		return t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return tsType(t.Elem(), indent)
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return tsType(t.Elem(), indent) + "[]"
+	case reflect.Map:
+		return "Record<string, " + tsType(t.Elem(), indent) + ">"
+	case reflect.Struct:
+		return tsFields(fieldsOf(t), indent)
+	}
+	return "unknown"
+}
@@ -0,0 +1,40 @@
+// Package query reads the optional integer parameters of the API: filters,
+// offsets and page sizes.
+package query
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalid is returned for a parameter that isn't a non-negative integer
+var ErrInvalid = errors.New("invalid query parameter")
+
+// Int reads an optional non-negative integer parameter, def when it is
+// missing
+func Int(c *gin.Context, key string, def int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, ErrInvalid
+	}
+	return n, nil
+}
+
+// Limit reads a page size: def when it is missing or 0, never more than
+// max
+func Limit(c *gin.Context, key string, def, max int) (int, error) {
+	n, err := Int(c, key, def)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		n = def
+	}
+	return min(n, max), nil
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func context(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+rawQuery, nil)
+	return c
+}
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		invalid bool
+	}{
+		{"", 20, false},
+		{"limit=0", 20, false},
+		{"limit=5", 5, false},
+		{"limit=100", 100, false},
+		{"limit=1000", 100, false},
+		{"limit=-1", 0, true},
+		{"limit=ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Limit(context(tt.query), "limit", 20, 100)
+		if (err != nil) != tt.invalid || got != tt.want {
+			t.Errorf("%q: Limit = %d, %v, want %d (invalid %v)", tt.query, got, err, tt.want, tt.invalid)
+		}
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		invalid bool
+	}{
+		{"", 7, false},
+		{"offset=0", 0, false},
+		{"offset=40", 40, false},
+		{"offset=-40", 0, true},
+		{"offset=4.5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Int(context(tt.query), "offset", 7)
+		if (err != nil) != tt.invalid || got != tt.want {
+			t.Errorf("%q: Int = %d, %v, want %d (invalid %v)", tt.query, got, err, tt.want, tt.invalid)
+		}
+	}
+}
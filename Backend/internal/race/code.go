@@ -0,0 +1,69 @@
+package race
+
+import (
+	"time"
+	"unicode"
+)
+
+// NewCode creates a race over source code. The text spans several lines:
+// words are the runs of non-whitespace characters and a completed word
+// takes the player to the start of the next one, over a newline too. With
+// autoIndent the indentation of every line is filled in for the players
+// and doesn't count as typed; otherwise it has to be typed and is part of
+// the first word of the line. With autoIndent, typing the indentation
+// anyway is typing past it: every whitespace key is a wrong key, counted
+// as an error, and the player goes on from the first word of the line.
+func NewCode(text string, startTime time.Time, autoIndent bool) (*Race, error) {
+	return newRace(text, startTime, autoIndent)
+}
+
+// split cuts the text into words. Whitespace separates words, except for
+// the indentation of a line, which without autoIndent belongs to the first
+// word of the line. With autoIndent, every whitespace rune following a
+// newline in a run of whitespace is marked as filled in. Text with single
+// spaces between words splits like strings.Split(text, " ").
+func (r *Race) split(autoIndent bool) {
+	if autoIndent {
+		r.auto = make([]bool, len(r.text))
+	}
+
+	i := 0
+	for i < len(r.text) {
+		sep := i
+		lineStart := -1 // offset following the last newline of the run
+		for ; i < len(r.text) && unicode.IsSpace(r.text[i]); i++ {
+			if lineStart >= 0 && r.auto != nil {
+				r.auto[i] = true
+			}
+			if r.text[i] == '\n' {
+				lineStart = i + 1
+			}
+		}
+		if i == len(r.text) {
+			break
+		}
+
+		start := i
+		if r.auto == nil {
+			// The indentation goes with the word
+			switch {
+			case sep == 0:
+				start = 0
+			case lineStart >= 0:
+				start = lineStart
+			}
+		}
+		for i < len(r.text) && !unicode.IsSpace(r.text[i]) {
+			i++
+		}
+		r.words = append(r.words, string(r.text[start:i]))
+		r.wordStart = append(r.wordStart, start)
+	}
+}
+
+// skipAuto moves the player over the characters filled in for it
+func (r *Race) skipAuto(p *Player) {
+	for r.auto != nil && p.Position < len(r.text) && r.auto[p.Position] {
+		p.Position++
+	}
+}
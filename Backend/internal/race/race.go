@@ -0,0 +1,385 @@
+// Package race implements the server-authoritative typing race engine.
+// A Race owns the text being typed, verifies every submission against it
+// and computes position, WPM, accuracy and finish order itself, so the
+// numbers broadcast to a room never come from what a browser claims.
+package race
+
+import (
+	"errors"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ManogyaDahal/GoType/internal/round"
+	gotext "github.com/ManogyaDahal/GoType/internal/text"
+)
+
+// Errors returned by Submit
+var (
+	ErrUnknownPlayer  = errors.New("player is not part of this race")
+	ErrNotStarted     = errors.New("race has not started yet")
+	ErrAlreadyDone    = errors.New("player already finished the race")
+	ErrWordOutOfOrder = errors.New("word submitted out of order")
+	ErrEmptyInput     = errors.New("submission carries no input")
+	ErrTimeUp         = errors.New("the race time is up")
+	ErrEmptyText      = errors.New("race text has no words")
+)
+
+// Input is one submission from a client. A client may send a completed
+// word (WordIndex + Word), a batch of raw keystrokes (Keys), or both.
+// Pos is only a cursor hint used to draw the ghost cursor inside the word
+// currently being typed; it never counts towards the verified position.
+type Input struct {
+	WordIndex *int    `json:"word_index,omitempty"`
+	Word      *string `json:"word,omitempty"`
+	Keys      string  `json:"keys,omitempty"`
+	Pos       *int    `json:"pos,omitempty"`
+}
+
+// Player is the race state of a single participant
+type Player struct {
+	ID         string
+	Name       string
+	Position   int // runes of the text done, whitespace and filled-in indentation included
+	Cursor     int // display cursor, never behind Position
+	Correct    int // correct keystrokes
+	Errors     int // rejected keystrokes / words
+	Finished   bool
+	Place      int       // 1-based finish position, 0 while racing
+	FinishedAt time.Time // when the last character was verified
+	Left       bool      // gone for good, the race no longer waits for them
+}
+
+// Progress is the authoritative snapshot of a player that gets broadcast
+type Progress struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Pos      int     `json:"pos"`
+	WPM      float64 `json:"wpm"`
+	Accuracy float64 `json:"accuracy"`
+	Finished bool    `json:"finished"`
+	Place    int     `json:"place,omitempty"`
+	TimeMs   int64   `json:"time_ms,omitempty"`
+	Chars    int     `json:"chars"` // correctly typed grapheme clusters
+}
+
+// Race is the state of one round. It is not safe for concurrent use; the
+// owning hub only touches it from its Run loop.
+type Race struct {
+	text      []rune
+	words     []string
+	wordStart []int  // rune offset of every word in text
+	auto      []bool // runes filled in for the players (auto-indent), nil for none
+	clusters  []int  // typed grapheme clusters before every rune offset
+	startTime time.Time
+	deadline  time.Time // end of a timed race, zero when untimed
+	players   map[string]*Player
+	finishers []string // player IDs in finish order
+}
+
+// New creates a race over text that starts at startTime. A text without
+// words can't be raced, ErrEmptyText is returned for it.
+func New(text string, startTime time.Time) (*Race, error) {
+	return newRace(text, startTime, false)
+}
+
+func newRace(text string, startTime time.Time, autoIndent bool) (*Race, error) {
+	r := &Race{
+		text:      []rune(text),
+		startTime: startTime,
+		players:   make(map[string]*Player),
+	}
+	r.split(autoIndent)
+	if len(r.words) == 0 {
+		return nil, ErrEmptyText
+	}
+	r.clusters = gotext.GraphemePrefixes(r.text)
+	if r.auto != nil {
+		skipped := 0
+		for i := range r.clusters {
+			r.clusters[i] -= skipped
+			if i < len(r.auto) && r.auto[i] {
+				skipped++
+			}
+		}
+	}
+	return r, nil
+}
+
+// NewTimed creates a race that stops accepting input after limit. Players
+// type until the deadline, their WPM is measured at the deadline and the
+// players that didn't complete the text are ranked by the characters they
+// typed.
+func NewTimed(text string, startTime time.Time, limit time.Duration) (*Race, error) {
+	r, err := New(text, startTime)
+	if err != nil {
+		return nil, err
+	}
+	r.deadline = startTime.Add(limit)
+	return r, nil
+}
+
+// Timed tells if the race has a deadline
+func (r *Race) Timed() bool { return !r.deadline.IsZero() }
+
+// Deadline returns the end of a timed race
+func (r *Race) Deadline() time.Time { return r.deadline }
+
+// Text returns the race text
+func (r *Race) Text() string { return string(r.text) }
+
+// Length returns the number of characters that have to be typed
+func (r *Race) Length() int { return len(r.text) }
+
+// StartTime returns the moment typing is allowed
+func (r *Race) StartTime() time.Time { return r.startTime }
+
+// Join adds a participant. Joining twice keeps the existing progress so a
+// player that reconnects mid-race continues where they left off.
+func (r *Race) Join(id, name string) *Player {
+	if p, ok := r.players[id]; ok {
+		p.Name = name
+		return p
+	}
+	p := &Player{ID: id, Name: name}
+	r.players[id] = p
+	return p
+}
+
+// Player returns the participant with the given id or nil
+func (r *Race) Player(id string) *Player {
+	return r.players[id]
+}
+
+// Abandon marks a participant as gone for good: the race stops waiting
+// for them, they end it DNF unless they finished
+func (r *Race) Abandon(id string) {
+	if p, ok := r.players[id]; ok {
+		p.Left = true
+	}
+}
+
+// Finished reports whether every participant has finished or left
+func (r *Race) Finished() bool {
+	if len(r.players) == 0 {
+		return false
+	}
+	for _, p := range r.players {
+		if !p.Finished && !p.Left {
+			return false
+		}
+	}
+	return true
+}
+
+// Submit verifies a submission against the race text and returns the
+// updated authoritative progress of the player.
+func (r *Race) Submit(id string, in Input, now time.Time) (Progress, error) {
+	p, ok := r.players[id]
+	if !ok {
+		return Progress{}, ErrUnknownPlayer
+	}
+	if now.Before(r.startTime) {
+		return r.progress(p, now), ErrNotStarted
+	}
+	if p.Finished {
+		return r.progress(p, now), ErrAlreadyDone
+	}
+	if r.Timed() && now.After(r.deadline) {
+		return r.progress(p, now), ErrTimeUp
+	}
+	if in.Word == nil && in.Keys == "" && in.Pos == nil {
+		return r.progress(p, now), ErrEmptyInput
+	}
+
+	var err error
+	if in.Word != nil {
+		err = r.submitWord(p, in)
+	}
+	if in.Keys != "" {
+		r.submitKeys(p, in.Keys)
+	}
+	if in.Pos != nil {
+		r.moveCursor(p, *in.Pos)
+	}
+	if p.Cursor < p.Position {
+		p.Cursor = p.Position
+	}
+
+	if p.Position >= len(r.text) && !p.Finished {
+		p.Finished = true
+		p.FinishedAt = now
+		r.finishers = append(r.finishers, p.ID)
+		p.Place = len(r.finishers)
+	}
+	return r.progress(p, now), err
+}
+
+// submitWord checks a completed word against the next expected word
+func (r *Race) submitWord(p *Player, in Input) error {
+	next := r.wordIndexAt(p.Position)
+	index := next
+	if in.WordIndex != nil {
+		index = *in.WordIndex
+	}
+	switch {
+	case index < next:
+		// Already verified, most likely a retried message
+		return nil
+	case index > next:
+		return ErrWordOutOfOrder
+	}
+
+	// Words can only be submitted from their first character
+	if p.Position != r.wordStart[index] {
+		return ErrWordOutOfOrder
+	}
+
+	expected := r.words[index]
+	if *in.Word != expected {
+		p.Errors++
+		return nil
+	}
+	// The word and the whitespace up to the next word, typed with one key
+	p.Correct += utf8.RuneCountInString(expected)
+	if index < len(r.words)-1 {
+		p.Correct++
+		p.Position = r.wordStart[index+1]
+	} else {
+		p.Position = len(r.text)
+	}
+	return nil
+}
+
+// submitKeys replays raw keystrokes from the current position. A wrong key
+// is counted as an error and does not advance the position.
+func (r *Race) submitKeys(p *Player, keys string) {
+	for _, k := range keys {
+		if p.Position >= len(r.text) {
+			return
+		}
+		if k == r.text[p.Position] {
+			p.Position++
+			p.Correct++
+			r.skipAuto(p)
+		} else {
+			p.Errors++
+		}
+	}
+}
+
+// moveCursor accepts a cursor hint as long as it stays inside the word the
+// player is currently typing
+func (r *Race) moveCursor(p *Player, pos int) {
+	limit := len(r.text)
+	if next := r.wordIndexAt(p.Position) + 1; next < len(r.wordStart) {
+		limit = r.wordStart[next]
+	}
+	if pos < p.Position {
+		pos = p.Position
+	}
+	if pos > limit {
+		pos = limit
+	}
+	p.Cursor = pos
+}
+
+// wordIndexAt returns the index of the word containing pos
+func (r *Race) wordIndexAt(pos int) int {
+	index := 0
+	for i, start := range r.wordStart {
+		if start > pos {
+			break
+		}
+		index = i
+	}
+	return index
+}
+
+// Progress returns the current snapshot of a player
+func (r *Race) Progress(id string, now time.Time) (Progress, bool) {
+	p, ok := r.players[id]
+	if !ok {
+		return Progress{}, false
+	}
+	return r.progress(p, now), true
+}
+
+// Standings returns the progress of every player, finishers first in finish
+// order followed by everybody else sorted by position. In a timed race the
+// others are sorted by correctly typed characters, which at a common
+// deadline is the same order as their WPM, then by accuracy.
+func (r *Race) Standings(now time.Time) []Progress {
+	standings := make([]Progress, 0, len(r.players))
+	for _, id := range r.finishers {
+		standings = append(standings, r.progress(r.players[id], now))
+	}
+	racing := make([]Progress, 0, len(r.players)-len(r.finishers))
+	for _, p := range r.players {
+		if !p.Finished {
+			racing = append(racing, r.progress(p, now))
+		}
+	}
+	sort.SliceStable(racing, func(i, j int) bool {
+		a, b := racing[i], racing[j]
+		if !r.Timed() {
+			return a.Pos > b.Pos
+		}
+		if a.Chars != b.Chars {
+			return a.Chars > b.Chars
+		}
+		if a.Accuracy != b.Accuracy {
+			return a.Accuracy > b.Accuracy
+		}
+		return a.ID < b.ID
+	})
+	return append(standings, racing...)
+}
+
+func (r *Race) progress(p *Player, now time.Time) Progress {
+	end := now
+	if p.Finished {
+		end = p.FinishedAt
+	}
+	if r.Timed() && end.After(r.deadline) {
+		end = r.deadline
+	}
+	elapsed := end.Sub(r.startTime)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	prog := Progress{
+		ID:       p.ID,
+		Name:     p.Name,
+		Pos:      p.Cursor,
+		WPM:      WPM(r.clusters[p.Position], elapsed),
+		Accuracy: Accuracy(p.Correct, p.Errors),
+		Finished: p.Finished,
+		Place:    p.Place,
+		Chars:    r.clusters[p.Position],
+	}
+	if p.Finished {
+		prog.TimeMs = elapsed.Milliseconds()
+	}
+	return prog
+}
+
+// WPM is the standard words per minute: (correct chars / 5) / minutes.
+// Matches calculateWPM in the frontend, including the 500ms warm-up.
+func WPM(correctChars int, elapsed time.Duration) float64 {
+	if elapsed < 500*time.Millisecond {
+		return 0
+	}
+	wpm := float64(correctChars) / 5 / elapsed.Minutes()
+	return round.Tenth(wpm)
+}
+
+// Accuracy is the percentage of correct keystrokes
+func Accuracy(correct, errors int) float64 {
+	total := correct + errors
+	if total == 0 {
+		return 100
+	}
+	return round.Tenth(float64(correct) * 100 / float64(total))
+}
@@ -0,0 +1,262 @@
+package race
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func word(index int, w string) Input { return Input{WordIndex: &index, Word: &w} }
+func keys(k string) Input            { return Input{Keys: k} }
+func cursor(pos int) Input           { return Input{Pos: &pos} }
+func ptr(s string) *string           { return &s }
+
+func TestSubmit(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		inputs   []Input
+		at       time.Duration // of the last input, 1s after the start when zero
+		limit    time.Duration // of a timed race
+		err      error         // returned for the last input
+		position int
+		cursor   int // when not the position
+		errors   int
+		finished bool
+	}{
+		{name: "words in order", inputs: []Input{word(0, "the"), word(1, "quick"), word(2, "fox")}, position: 13, finished: true},
+		{name: "words without index", inputs: []Input{{Word: ptr("the")}, {Word: ptr("quick")}}, position: 10},
+		{name: "wrong word", inputs: []Input{word(0, "teh")}, errors: 1},
+		{name: "word out of order", inputs: []Input{word(2, "fox")}, err: ErrWordOutOfOrder},
+		{name: "retried word", inputs: []Input{word(0, "the"), word(0, "the")}, position: 4},
+		{name: "word in the middle of a word", inputs: []Input{keys("th"), word(0, "the")}, err: ErrWordOutOfOrder, position: 2},
+		{name: "keys", inputs: []Input{keys("the q")}, position: 5},
+		{name: "wrong keys", inputs: []Input{keys("thx")}, position: 2, errors: 1},
+		{name: "keys then words", inputs: []Input{keys("the "), word(1, "quick"), keys("f")}, position: 11},
+		{name: "keys overflowing the text", inputs: []Input{keys("the quick fox and more")}, position: 13, finished: true},
+		{name: "cursor inside the word", inputs: []Input{keys("th"), cursor(3)}, position: 2, cursor: 3},
+		{name: "cursor past the word", inputs: []Input{keys("th"), cursor(12)}, position: 2, cursor: 4},
+		{name: "cursor behind the position", inputs: []Input{keys("the q"), cursor(1)}, position: 5},
+		{name: "empty input", inputs: []Input{{}}, err: ErrEmptyInput},
+		{name: "before the start", inputs: []Input{keys("t")}, at: -time.Second, err: ErrNotStarted},
+		{name: "after finishing", inputs: []Input{keys("the quick fox"), keys("x")}, err: ErrAlreadyDone, position: 13, finished: true},
+		{name: "after the deadline", inputs: []Input{keys("t")}, at: time.Minute, limit: 30 * time.Second, err: ErrTimeUp},
+	}
+	for _, tt := range tests {
+		r, err := New("the quick fox", start)
+		if tt.limit > 0 {
+			r, err = NewTimed("the quick fox", start, tt.limit)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Join("p", "Player")
+
+		at := tt.at
+		if at == 0 {
+			at = time.Second
+		}
+		for i, in := range tt.inputs {
+			now := start.Add(time.Second)
+			if i == len(tt.inputs)-1 {
+				now = start.Add(at)
+			}
+			_, err = r.Submit("p", in, now)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+		}
+		p := r.Player("p")
+		want := tt.cursor
+		if want == 0 {
+			want = tt.position
+		}
+		if p.Position != tt.position || p.Cursor != want || p.Errors != tt.errors || p.Finished != tt.finished {
+			t.Errorf("%s: position %d, cursor %d, errors %d, finished %v, want %d, %d, %d, %v",
+				tt.name, p.Position, p.Cursor, p.Errors, p.Finished, tt.position, want, tt.errors, tt.finished)
+		}
+	}
+}
+
+func TestFinishOrder(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r, err := New("go", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		r.Join(id, id)
+	}
+	r.Submit("b", keys("go"), start.Add(time.Second))
+	r.Submit("a", keys("go"), start.Add(2*time.Second))
+	if r.Finished() {
+		t.Fatal("finished while c is still racing")
+	}
+	r.Abandon("c")
+	if !r.Finished() {
+		t.Fatal("still waiting for a player who left")
+	}
+
+	standings := r.Standings(start.Add(3 * time.Second))
+	var order string
+	for _, p := range standings {
+		order += p.ID
+	}
+	if order != "bac" || standings[0].Place != 1 || standings[1].Place != 2 || standings[2].Finished {
+		t.Errorf("standings %+v, want b, a, then c unfinished", standings)
+	}
+}
+
+func TestNewEmptyText(t *testing.T) {
+	for _, text := range []string{"", "   ", "\n\t"} {
+		if _, err := New(text, time.Now()); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("New(%q) error %v, want ErrEmptyText", text, err)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	const text = "if x {\n    y()\n}"
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		autoIndent bool
+		inputs     []Input
+		position   int
+		correct    int
+		errors     int
+		finished   bool
+	}{
+		{name: "keys", inputs: []Input{keys(text)}, position: 16, correct: 16, finished: true},
+		{name: "keys without the indentation", inputs: []Input{keys("if x {\ny")}, position: 7, correct: 7, errors: 1},
+		{name: "words", inputs: []Input{word(0, "if"), word(1, "x"), word(2, "{"), word(3, "    y()"), word(4, "}")}, position: 16, correct: 16, finished: true},
+		{name: "word without its indentation", inputs: []Input{word(0, "if"), word(1, "x"), word(2, "{"), word(3, "y()")}, position: 7, correct: 7, errors: 1},
+		{name: "auto keys", autoIndent: true, inputs: []Input{keys("if x {\ny()\n}")}, position: 16, correct: 12, finished: true},
+		{name: "auto keys up to the indentation", autoIndent: true, inputs: []Input{keys("if x {\n")}, position: 11, correct: 7},
+		{name: "auto keys typing the indentation", autoIndent: true, inputs: []Input{keys(text)}, position: 16, correct: 12, errors: 4, finished: true},
+		{name: "auto words", autoIndent: true, inputs: []Input{word(0, "if"), word(1, "x"), word(2, "{"), word(3, "y()"), word(4, "}")}, position: 16, correct: 12, finished: true},
+		{name: "auto word with its indentation", autoIndent: true, inputs: []Input{word(0, "if"), word(1, "x"), word(2, "{"), word(3, "    y()")}, position: 11, correct: 7, errors: 1},
+	}
+	for _, tt := range tests {
+		r, err := NewCode(text, start, tt.autoIndent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Join("p", "Player")
+		for _, in := range tt.inputs {
+			if _, err := r.Submit("p", in, start.Add(time.Second)); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		p := r.Player("p")
+		if p.Position != tt.position || p.Correct != tt.correct || p.Errors != tt.errors || p.Finished != tt.finished {
+			t.Errorf("%s: position %d, correct %d, errors %d, finished %v, want %d, %d, %d, %v",
+				tt.name, p.Position, p.Correct, p.Errors, p.Finished, tt.position, tt.correct, tt.errors, tt.finished)
+		}
+	}
+
+	for _, autoIndent := range []bool{false, true} {
+		if _, err := NewCode("\n    \n", start, autoIndent); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("NewCode of whitespace, autoIndent %v: error %v, want ErrEmptyText", autoIndent, err)
+		}
+	}
+}
+
+func TestTimedStandings(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	type submit struct {
+		id   string
+		keys string
+		at   time.Duration
+	}
+	tests := []struct {
+		name     string
+		submits  []submit
+		order    string  // IDs in standing order
+		wpm      float64 // of the first one, measured at the deadline
+		finished bool    // the first one
+	}{
+		{name: "nobody typed", order: "abc"},
+		{
+			name:    "by chars",
+			submits: []submit{{"a", "the q", 10 * time.Second}, {"b", "the qu", 10 * time.Second}},
+			order:   "bac", wpm: 2.4,
+		},
+		{
+			name:    "then by accuracy",
+			submits: []submit{{"a", "thxe q", 10 * time.Second}, {"b", "the q", 10 * time.Second}},
+			order:   "bac", wpm: 2,
+		},
+		{
+			name:    "then by ID",
+			submits: []submit{{"c", "the", 10 * time.Second}, {"b", "the", 20 * time.Second}},
+			order:   "bca", wpm: 1.2,
+		},
+		{
+			name:    "finishers first",
+			submits: []submit{{"a", "the quick f", 10 * time.Second}, {"c", "the quick fox", 20 * time.Second}},
+			order:   "cab", wpm: 7.8, finished: true,
+		},
+		{
+			name:    "keys after the deadline ignored",
+			submits: []submit{{"a", "the", 10 * time.Second}, {"a", " quick", 31 * time.Second}, {"b", "the q", 10 * time.Second}},
+			order:   "bac", wpm: 2,
+		},
+	}
+	for _, tt := range tests {
+		r, err := NewTimed("the quick fox", start, 30*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"c", "a", "b"} {
+			r.Join(id, id)
+		}
+		for _, s := range tt.submits {
+			r.Submit(s.id, keys(s.keys), start.Add(s.at))
+		}
+
+		standings := r.Standings(start.Add(time.Minute))
+		var order string
+		for _, p := range standings {
+			order += p.ID
+		}
+		if order != tt.order {
+			t.Errorf("%s: order %q, want %q", tt.name, order, tt.order)
+			continue
+		}
+		if first := standings[0]; first.WPM != tt.wpm || first.Finished != tt.finished {
+			t.Errorf("%s: first %+v, want WPM %v, finished %v", tt.name, first, tt.wpm, tt.finished)
+		}
+	}
+}
+
+func TestTimedDeadline(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r, err := NewTimed("the quick fox", start, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Join("p", "Player")
+	if !r.Timed() || !r.Deadline().Equal(start.Add(30*time.Second)) {
+		t.Fatalf("deadline %v, want 30s after the start", r.Deadline())
+	}
+	for _, tt := range []struct {
+		keys string
+		at   time.Duration
+		err  error
+	}{
+		{"t", 29 * time.Second, nil},
+		{"h", 30 * time.Second, nil},
+		{"e", 30*time.Second + time.Millisecond, ErrTimeUp},
+	} {
+		if _, err := r.Submit("p", keys(tt.keys), start.Add(tt.at)); !errors.Is(err, tt.err) {
+			t.Errorf("keys %v after the start: error %v, want %v", tt.at, err, tt.err)
+		}
+	}
+	if p := r.Player("p"); p.Position != 2 {
+		t.Errorf("position %d, want 2", p.Position)
+	}
+	if r.Finished() {
+		t.Error("timed race finished while the player still races")
+	}
+}
@@ -0,0 +1,177 @@
+// Package rating keeps a skill rating per user. A multiplayer race is
+// scored as a set of pairwise Elo matches: every participant "plays" every
+// other one and wins against those placed behind it. The rating changes of
+// a race are averaged over the opponents so a crowded race moves ratings no
+// more than a one on one race.
+package rating
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/round"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// Rating parameters
+const (
+	Initial = 1500.0 // rating of a user without rated races
+
+	K                = 32.0 // maximum change per race
+	ProvisionalK     = 48.0 // used while a user has few rated races
+	ProvisionalRaces = 10   // rated races before K applies
+
+	// MinParticipants is the number of players a race needs to be rated
+	MinParticipants = 2
+)
+
+// Participant is one player of a rated race
+type Participant struct {
+	UserID string
+	Rating float64
+	Races  int  // rated races so far, selects the K factor
+	Place  int  // 1-based placement
+	DNF    bool // did not finish, DNF players tie with each other
+}
+
+// Expected returns the expected score of a player rated a against b
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Deltas returns the rating change of every participant, in order
+func Deltas(ps []Participant) []float64 {
+	deltas := make([]float64, len(ps))
+	if len(ps) < MinParticipants {
+		return deltas
+	}
+
+	for i, p := range ps {
+		var score, expected float64
+		for j, o := range ps {
+			if i == j {
+				continue
+			}
+			score += pairScore(p, o)
+			expected += Expected(p.Rating, o.Rating)
+		}
+		k := K
+		if p.Races < ProvisionalRaces {
+			k = ProvisionalK
+		}
+		deltas[i] = round.Tenth(k * (score - expected) / float64(len(ps)-1))
+	}
+	return deltas
+}
+
+// pairScore is 1 when p beat o, 0 when it lost and 0.5 for a tie
+func pairScore(p, o Participant) float64 {
+	switch {
+	case p.DNF && o.DNF:
+		return 0.5
+	case p.DNF:
+		return 0
+	case o.DNF:
+		return 1
+	case p.Place < o.Place:
+		return 1
+	case p.Place > o.Place:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+// Lookup returns the rating of a user, Initial when it has none yet
+func Lookup(store storage.Store, userID string) (storage.Rating, error) {
+	r, err := store.GetRating(userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Rating{UserID: userID, Rating: Initial}, nil
+	}
+	return r, err
+}
+
+// Updater applies finished races to the stored ratings. Hubs finish races
+// concurrently, the updater serializes the read-modify-write of ratings.
+type Updater struct {
+	store storage.Store
+	mu    sync.Mutex
+}
+
+// NewUpdater returns an updater writing to store
+func NewUpdater(store storage.Store) *Updater {
+	return &Updater{store: store}
+}
+
+// Apply updates the rating of every participant of the race and records
+// the changes in their history. Unranked races and races with fewer than
+// MinParticipants players (e.g. someone racing alone) are not rated.
+func (u *Updater) Apply(race storage.Race) ([]storage.RatingChange, error) {
+	if race.Unranked {
+		return nil, nil
+	}
+	results := make([]storage.Result, 0, len(race.Results))
+	for _, r := range race.Results {
+		// Flagged results neither gain rating nor cost others any
+		if r.UserID != "" && !r.Flagged {
+			results = append(results, r)
+		}
+	}
+	if len(results) < MinParticipants {
+		return nil, nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	current := make([]storage.Rating, len(results))
+	ps := make([]Participant, len(results))
+	for i, r := range results {
+		rating, err := Lookup(u.store, r.UserID)
+		if err != nil {
+			return nil, err
+		}
+		current[i] = rating
+		ps[i] = Participant{
+			UserID: r.UserID,
+			Rating: rating.Rating,
+			Races:  rating.Races,
+			Place:  r.Place,
+			DNF:    !r.Finished,
+		}
+	}
+
+	at := race.FinishedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	deltas := Deltas(ps)
+	ratings := make([]storage.Rating, len(results))
+	changes := make([]storage.RatingChange, len(results))
+	for i, p := range ps {
+		after := round.Tenth(p.Rating + deltas[i])
+		ratings[i] = storage.Rating{
+			UserID:    p.UserID,
+			Rating:    after,
+			Races:     current[i].Races + 1,
+			UpdatedAt: at,
+		}
+		changes[i] = storage.RatingChange{
+			UserID:       p.UserID,
+			RaceID:       race.ID,
+			Before:       p.Rating,
+			After:        after,
+			Delta:        deltas[i],
+			Place:        p.Place,
+			Participants: len(ps),
+			At:           at,
+		}
+	}
+
+	if err := u.store.SaveRatings(ratings, changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,132 @@
+package rating
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+func TestDeltas(t *testing.T) {
+	tests := []struct {
+		name string
+		ps   []Participant
+		want []float64
+	}{
+		{
+			name: "alone",
+			ps:   []Participant{{Rating: 1500, Races: 20, Place: 1}},
+			want: []float64{0},
+		},
+		{
+			name: "one on one",
+			ps:   []Participant{{Rating: 1500, Races: 20, Place: 1}, {Rating: 1500, Races: 20, Place: 2}},
+			want: []float64{16, -16},
+		},
+		{
+			name: "tie",
+			ps:   []Participant{{Rating: 1500, Races: 20, Place: 1}, {Rating: 1500, Races: 20, Place: 1}},
+			want: []float64{0, 0},
+		},
+		{
+			name: "both DNF",
+			ps:   []Participant{{Rating: 1500, Races: 20, DNF: true}, {Rating: 1500, Races: 20, DNF: true}},
+			want: []float64{0, 0},
+		},
+		{
+			name: "underdog wins",
+			ps:   []Participant{{Rating: 1400, Races: 20, Place: 1}, {Rating: 1600, Races: 20, Place: 2}},
+			want: []float64{24.3, -24.3},
+		},
+		{
+			name: "favourite wins",
+			ps:   []Participant{{Rating: 1600, Races: 20, Place: 1}, {Rating: 1400, Races: 20, Place: 2}},
+			want: []float64{7.7, -7.7},
+		},
+		{
+			name: "provisional player",
+			ps:   []Participant{{Rating: 1500, Races: 0, Place: 1}, {Rating: 1500, Races: 20, Place: 2}},
+			want: []float64{24, -16},
+		},
+		{
+			name: "three players",
+			ps: []Participant{
+				{Rating: 1500, Races: 20, Place: 1},
+				{Rating: 1500, Races: 20, Place: 2},
+				{Rating: 1500, Races: 20, Place: 3},
+			},
+			want: []float64{16, 0, -16},
+		},
+		{
+			name: "tie for first of three",
+			ps: []Participant{
+				{Rating: 1500, Races: 20, Place: 1},
+				{Rating: 1500, Races: 20, Place: 1},
+				{Rating: 1500, Races: 20, Place: 3},
+			},
+			want: []float64{8, 8, -16},
+		},
+		{
+			name: "DNF of three",
+			ps: []Participant{
+				{Rating: 1500, Races: 20, Place: 1},
+				{Rating: 1500, Races: 20, DNF: true},
+				{Rating: 1500, Races: 20, Place: 2},
+			},
+			want: []float64{16, -16, 0},
+		},
+		{
+			name: "four players, two DNF",
+			ps: []Participant{
+				{Rating: 1500, Races: 20, Place: 1},
+				{Rating: 1500, Races: 20, Place: 2},
+				{Rating: 1500, Races: 20, DNF: true},
+				{Rating: 1500, Races: 20, DNF: true},
+			},
+			want: []float64{16, 5.3, -10.7, -10.7},
+		},
+	}
+	for _, tt := range tests {
+		if got := Deltas(tt.ps); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Deltas = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	store := storage.NewMemoryStore()
+	u := NewUpdater(store)
+	race := storage.Race{ID: "r", Results: []storage.Result{
+		{UserID: "a", Place: 1, Finished: true},
+		{UserID: "b", Place: 2, Finished: true},
+		{UserID: "c", Place: 3, Finished: true, Flagged: true},
+		{Place: 4, Finished: true}, // guest
+	}}
+	changes, err := u.Apply(race)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Delta != 24 || changes[1].Delta != -24 || changes[0].Participants != 2 {
+		t.Errorf("changes %+v, want a +24 and b -24, provisional, out of 2", changes)
+	}
+	if r, _ := Lookup(store, "a"); r.Rating != Initial+24 || r.Races != 1 {
+		t.Errorf("rating of a %+v", r)
+	}
+	if _, err := store.GetRating("c"); err != storage.ErrNotFound {
+		t.Errorf("flagged player rated: %v", err)
+	}
+
+	// Alone once the others are left out, not rated
+	alone := storage.Race{ID: "s", Results: []storage.Result{{UserID: "a", Place: 1, Finished: true}, {UserID: "c", Flagged: true}}}
+	if changes, err := u.Apply(alone); err != nil || changes != nil {
+		t.Errorf("race of one rated: %+v, %v", changes, err)
+	}
+
+	unranked := storage.Race{ID: "t", Unranked: true, Results: []storage.Result{
+		{UserID: "a", Place: 1, Finished: true},
+		{UserID: "b", Place: 2, Finished: true},
+	}}
+	if changes, err := u.Apply(unranked); err != nil || changes != nil {
+		t.Errorf("unranked race rated: %+v, %v", changes, err)
+	}
+}
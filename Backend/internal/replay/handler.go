@@ -0,0 +1,33 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves GET /api/races/:id/replay, the replay of a race as a JSON
+// download
+func Handler(store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raceID := c.Param("id")
+		rep, err := store.GetReplay(raceID)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Replay not found"})
+			return
+		}
+		if err != nil {
+			logger.Logger.Error("[Replay] Failed to load replay", "raceId", raceID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load replay"})
+			return
+		}
+
+		c.Header("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="race-%s.replay.json"`, raceID))
+		c.JSON(http.StatusOK, rep)
+	}
+}
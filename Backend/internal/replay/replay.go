@@ -0,0 +1,188 @@
+// Package replay records the progress stream of a race and turns it into a
+// compact replay. A replay can be downloaded or raced against: the hub
+// plays a recorded player back as a "ghost" next to the live players.
+//
+// A track is stored as a list of [ms, pos, wpm×10] integer triples. ms is
+// the time since the previous frame (since the race start for the first
+// one) so a track of a few hundred frames stays small once JSON encoded.
+package replay
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// MaxFrames caps the frames recorded per player and race
+const MaxFrames = 5000
+
+// ErrNoReplay is returned when no replay matches a ghost request
+var ErrNoReplay = errors.New("no replay available")
+
+// Frame is one decoded point of a track
+type Frame struct {
+	At  time.Duration // since the race start
+	Pos int
+	WPM float64
+}
+
+// Encode turns frames, in time order, into the compact track format
+func Encode(frames []Frame) [][3]int64 {
+	out := make([][3]int64, len(frames))
+	var last int64
+	for i, f := range frames {
+		ms := f.At.Milliseconds()
+		out[i] = [3]int64{ms - last, int64(f.Pos), int64(math.Round(f.WPM * 10))}
+		last = ms
+	}
+	return out
+}
+
+// Decode turns a compact track back into frames
+func Decode(encoded [][3]int64) []Frame {
+	out := make([]Frame, len(encoded))
+	var ms int64
+	for i, e := range encoded {
+		ms += e[0]
+		out[i] = Frame{
+			At:  time.Duration(ms) * time.Millisecond,
+			Pos: int(e[1]),
+			WPM: float64(e[2]) / 10,
+		}
+	}
+	return out
+}
+
+// track is a track being recorded
+type track struct {
+	name   string
+	frames []Frame
+}
+
+// Recorder collects the progress stream of one race. It is not safe for
+// concurrent use, the owning hub only touches it from its Run loop.
+type Recorder struct {
+	text     string
+	mode     string
+	language string
+	start    time.Time
+	order    []string // user IDs in the order they first moved
+	tracks   map[string]*track
+}
+
+// NewRecorder starts recording a race of the given mode and language over
+// text that starts at start
+func NewRecorder(text, mode, language string, start time.Time) *Recorder {
+	return &Recorder{
+		text:     text,
+		mode:     mode,
+		language: language,
+		start:    start,
+		tracks:   make(map[string]*track),
+	}
+}
+
+// Add records the verified position of a player. Frames that don't move
+// the player are dropped.
+func (r *Recorder) Add(userID, name string, pos int, wpm float64, at time.Time) {
+	t, ok := r.tracks[userID]
+	if !ok {
+		t = &track{}
+		r.tracks[userID] = t
+		r.order = append(r.order, userID)
+	}
+	t.name = name
+	if n := len(t.frames); n >= MaxFrames || (n > 0 && t.frames[n-1].Pos == pos) {
+		return
+	}
+	offset := at.Sub(r.start)
+	if offset < 0 {
+		offset = 0
+	}
+	t.frames = append(t.frames, Frame{At: offset, Pos: pos, WPM: wpm})
+}
+
+// Replay returns the replay of the race stored as raceID. finished holds
+// the players that completed the text.
+func (r *Recorder) Replay(raceID string, finished map[string]bool) storage.Replay {
+	rep := storage.Replay{
+		RaceID:    raceID,
+		Text:      r.text,
+		Mode:      r.mode,
+		Language:  r.language,
+		StartedAt: r.start,
+		Tracks:    make([]storage.ReplayTrack, 0, len(r.order)),
+	}
+	for _, id := range r.order {
+		t := r.tracks[id]
+		rep.Tracks = append(rep.Tracks, storage.ReplayTrack{
+			UserID:   id,
+			Name:     t.name,
+			Finished: finished[id],
+			Frames:   Encode(t.frames),
+		})
+	}
+	return rep
+}
+
+// Duration returns how long the track took, up to its last frame
+func Duration(t storage.ReplayTrack) time.Duration {
+	var ms int64
+	for _, f := range t.Frames {
+		ms += f[0]
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Fastest returns the track of the replay's winner: the finished track
+// that took the least time
+func Fastest(rep storage.Replay) (storage.ReplayTrack, bool) {
+	var (
+		best  storage.ReplayTrack
+		found bool
+	)
+	for _, t := range rep.Tracks {
+		if t.Finished && (!found || Duration(t) < Duration(best)) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// Best returns the replay of the fastest verified, unflagged race of the
+// given mode the user finished
+func Best(store storage.Store, userID, mode string) (storage.Replay, error) {
+	races, err := store.ListRaces(storage.RaceFilter{UserID: userID, Mode: mode, VerifiedOnly: true})
+	if err != nil {
+		return storage.Replay{}, err
+	}
+
+	var (
+		best    storage.Replay
+		bestWPM float64
+		found   bool
+	)
+	for _, race := range races {
+		res, ok := race.Result(userID)
+		if !ok || !res.Finished || res.Flagged || (found && res.WPM <= bestWPM) {
+			continue
+		}
+		rep, err := store.GetReplay(race.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return storage.Replay{}, err
+		}
+		if _, ok := rep.Track(userID); !ok {
+			continue
+		}
+		best, bestWPM, found = rep, res.WPM, true
+	}
+	if !found {
+		return storage.Replay{}, ErrNoReplay
+	}
+	return best, nil
+}
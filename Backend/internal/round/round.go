@@ -0,0 +1,10 @@
+// Package round rounds the figures the server reports: WPM, accuracy,
+// ratings and the like.
+package round
+
+import "math"
+
+// Tenth rounds v to one decimal, halves away from zero
+func Tenth(v float64) float64 {
+	return math.Round(v*10) / 10
+}
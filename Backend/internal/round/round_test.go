@@ -0,0 +1,23 @@
+package round
+
+import "testing"
+
+func TestTenth(t *testing.T) {
+	tests := []struct {
+		v, want float64
+	}{
+		{0, 0},
+		{87.46, 87.5},
+		{87.44, 87.4},
+		{12.25, 12.3},
+		{-12.25, -12.3},
+		{-3.14, -3.1},
+		{-0.04, 0},
+		{-7.96, -8},
+	}
+	for _, tt := range tests {
+		if got := Tenth(tt.v); got != tt.want {
+			t.Errorf("Tenth(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"os"
 	"time"
 
+	"github.com/ManogyaDahal/GoType/internal/analytics"
 	"github.com/ManogyaDahal/GoType/internal/auth"
+	"github.com/ManogyaDahal/GoType/internal/leaderboard"
+	"github.com/ManogyaDahal/GoType/internal/profile"
+	"github.com/ManogyaDahal/GoType/internal/replay"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/ManogyaDahal/GoType/internal/text"
 	"github.com/ManogyaDahal/GoType/internal/websockets"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 // Sets Up the routers and defines all the routes
-func SetupRouters(manager *websockets.HubManager) *gin.Engine {
+func SetupRouters(manager *websockets.HubManager, store storage.Store) *gin.Engine {
 	if os.Getenv("ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -41,12 +47,23 @@ func SetupRouters(manager *websockets.HubManager) *gin.Engine {
 	// defining routes
 	router.GET("/", auth.HomeHandler)
 	router.GET("/login", auth.LoginHandler(cfg))
-	router.GET("/api/whoamI", auth.WhoAmI)
-	router.GET("/auth/google/callback", auth.CallbackHandler(cfg))
+	router.GET("/api/whoamI", auth.WhoAmI(store))
+	router.GET("/auth/google/callback", auth.CallbackHandler(cfg, store))
 	router.GET("/logout", auth.LogoutHandler)
 
 	router.GET("/ws", websockets.AuthenticatedWSHandler(manager))
 	router.POST("/api/create-room", websockets.CreateNewRoom(manager))
+	router.GET("/api/rooms", websockets.ListRooms(manager))
+	router.POST("/api/quick-match", websockets.QuickMatch(manager))
+	router.GET("/api/rooms/:id", websockets.RoomDetails(manager))
+	router.POST("/api/rooms/:id/close", websockets.CloseRoom(manager))
+
+	router.GET("/api/leaderboard", leaderboard.Handler(store))
+	router.GET("/api/users/:id", profile.Handler(store))
+	router.GET("/api/me/history", profile.HistoryHandler(store))
+	router.GET("/api/me/analytics", analytics.Handler(store))
+	router.GET("/api/races/:id/replay", replay.Handler(store))
+	router.GET("/api/languages", text.LanguagesHandler())
 
 	return router
 }
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Number of changes appended to the log before it is folded into a new
+// snapshot
+const compactEvery = 1000
+
+// FileStore is a MemoryStore persisted to disk. Every change is appended
+// to a log next to the snapshot file, the log is folded into a new
+// snapshot when the store is opened, every compactEvery changes and on
+// Close. The snapshot is written to a temp file first and renamed, so a
+// crash never leaves a half written file behind; a change cut short by a
+// crash is dropped from the end of the log.
+type FileStore struct {
+	*MemoryStore
+	path    string
+	writeMu sync.Mutex // serializes changes, so the log has them in order
+	log     *os.File
+	seq     uint64 // sequence number of the last change
+	logged  int    // changes in the log since the last snapshot
+}
+
+// snapshot is the on-disk format
+type snapshot struct {
+	Seq uint64 `json:"seq"` // last change of the log included
+
+	Users []User `json:"users"`
+	Rooms []Room `json:"rooms"`
+	Races []Race `json:"races"` // oldest first
+
+	Ratings       []Rating       `json:"ratings"`
+	RatingHistory []RatingChange `json:"rating_history"` // oldest first
+
+	TypingStats []TypingStats `json:"typing_stats"`
+	Replays     []Replay      `json:"replays"`
+}
+
+// change is one record of the log, a single change is set
+type change struct {
+	Seq uint64 `json:"seq"`
+
+	User          *User          `json:"user,omitempty"`
+	Room          *Room          `json:"room,omitempty"`
+	Race          *Race          `json:"race,omitempty"`
+	Ratings       []Rating       `json:"ratings,omitempty"`
+	RatingChanges []RatingChange `json:"rating_changes,omitempty"`
+	TypingStats   *TypingStats   `json:"typing_stats,omitempty"`
+	Replay        *Replay        `json:"replay,omitempty"`
+}
+
+// applyTo makes the change in a memory store
+func (c change) applyTo(s *MemoryStore) error {
+	switch {
+	case c.User != nil:
+		return s.UpsertUser(*c.User)
+	case c.Room != nil:
+		return s.SaveRoom(*c.Room)
+	case c.Race != nil:
+		return s.SaveRace(*c.Race)
+	case c.Ratings != nil || c.RatingChanges != nil:
+		return s.SaveRatings(c.Ratings, c.RatingChanges)
+	case c.TypingStats != nil:
+		return s.SaveTypingStats(*c.TypingStats)
+	case c.Replay != nil:
+		return s.SaveReplay(*c.Replay)
+	}
+	return nil
+}
+
+// NewFileStore opens (or creates) the store persisted at path, its log is
+// path + ".log"
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("storage: read %s: %w", path, err)
+	}
+	if err == nil {
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return nil, fmt.Errorf("storage: decode %s: %w", path, err)
+		}
+		s.load(snap)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("storage: create dir: %w", err)
+		}
+	}
+	logPath := path + ".log"
+	if s.log, err = os.OpenFile(logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600); err != nil {
+		return nil, fmt.Errorf("storage: open %s: %w", logPath, err)
+	}
+	if err := s.replay(); err != nil {
+		s.log.Close()
+		return nil, fmt.Errorf("storage: replay %s: %w", logPath, err)
+	}
+	if info, err := s.log.Stat(); err == nil && info.Size() > 0 {
+		if err := s.compact(); err != nil {
+			s.log.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// load fills the memory store from a snapshot
+func (s *FileStore) load(snap snapshot) {
+	s.seq = snap.Seq
+	for _, u := range snap.Users {
+		s.users[u.ID] = u
+	}
+	for _, r := range snap.Rooms {
+		s.rooms[r.ID] = r
+	}
+	s.setRaces(snap.Races)
+	for _, r := range snap.Ratings {
+		s.ratings[r.UserID] = r
+	}
+	for _, c := range snap.RatingHistory {
+		s.ratingHistory[c.UserID] = append(s.ratingHistory[c.UserID], c)
+	}
+	for _, t := range snap.TypingStats {
+		s.typingStats[t.UserID] = t
+	}
+	for _, r := range snap.Replays {
+		s.replays[r.RaceID] = r
+	}
+}
+
+// replay applies the changes of the log the snapshot doesn't have yet. A
+// record cut short at the end of the log is ignored.
+func (s *FileStore) replay() error {
+	dec := json.NewDecoder(s.log)
+	for {
+		var c change
+		err := dec.Decode(&c)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c.Seq <= s.seq {
+			continue
+		}
+		if err := c.applyTo(s.MemoryStore); err != nil {
+			return err
+		}
+		s.seq = c.Seq
+	}
+}
+
+func (s *FileStore) UpsertUser(u User) error {
+	return s.apply(change{User: &u})
+}
+
+func (s *FileStore) SaveRoom(r Room) error {
+	return s.apply(change{Room: &r})
+}
+
+func (s *FileStore) SaveRace(r Race) error {
+	return s.apply(change{Race: &r})
+}
+
+func (s *FileStore) SaveRatings(ratings []Rating, changes []RatingChange) error {
+	return s.apply(change{Ratings: ratings, RatingChanges: changes})
+}
+
+func (s *FileStore) SaveTypingStats(t TypingStats) error {
+	return s.apply(change{TypingStats: &t})
+}
+
+func (s *FileStore) SaveReplay(r Replay) error {
+	return s.apply(change{Replay: &r})
+}
+
+func (s *FileStore) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	err := s.compact()
+	if cerr := s.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// apply makes a change in memory and appends it to the log
+func (s *FileStore) apply(c change) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := c.applyTo(s.MemoryStore); err != nil {
+		return err
+	}
+	s.seq++
+	c.Seq = s.seq
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("storage: encode: %w", err)
+	}
+	if _, err := s.log.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("storage: append to log: %w", err)
+	}
+	if s.logged++; s.logged >= compactEvery {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact writes the current data to a new snapshot and empties the log.
+// The caller holds writeMu.
+func (s *FileStore) compact() error {
+	s.mu.RLock()
+	snap := snapshot{
+		Seq:   s.seq,
+		Users: make([]User, 0, len(s.users)),
+		Rooms: make([]Room, 0, len(s.rooms)),
+		Races: s.races,
+	}
+	for _, u := range s.users {
+		snap.Users = append(snap.Users, u)
+	}
+	for _, r := range s.rooms {
+		snap.Rooms = append(snap.Rooms, r)
+	}
+	for _, r := range s.ratings {
+		snap.Ratings = append(snap.Ratings, r)
+	}
+	for _, history := range s.ratingHistory {
+		snap.RatingHistory = append(snap.RatingHistory, history...)
+	}
+	for _, t := range s.typingStats {
+		snap.TypingStats = append(snap.TypingStats, t)
+	}
+	for _, r := range s.replays {
+		snap.Replays = append(snap.Replays, r)
+	}
+	data, err := json.Marshal(snap)
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("storage: encode: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("storage: write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("storage: rename %s: %w", tmp, err)
+	}
+
+	// The snapshot has every change now; were the log not emptied, the
+	// sequence numbers keep its changes from being applied twice
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("storage: truncate log: %w", err)
+	}
+	s.logged = 0
+	return nil
+}
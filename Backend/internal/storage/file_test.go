@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openFile(t *testing.T, path string) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// fill saves a user, two races out of finish order and a rating change
+func fill(t *testing.T, s Store) {
+	t.Helper()
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, err := range []error{
+		s.UpsertUser(User{ID: "u", Name: "Ann"}),
+		s.SaveRace(Race{ID: "late", FinishedAt: at.Add(time.Minute)}),
+		s.SaveRace(Race{ID: "early", FinishedAt: at}),
+		s.SaveRatings([]Rating{{UserID: "u", Rating: 1016}}, []RatingChange{{UserID: "u", RaceID: "late", Delta: 16}}),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// check tells if s holds what fill saved, once
+func check(t *testing.T, s Store) {
+	t.Helper()
+	if u, err := s.GetUser("u"); err != nil || u.Name != "Ann" {
+		t.Errorf("user %+v, %v", u, err)
+	}
+	races, _ := s.ListRaces(RaceFilter{})
+	if len(races) != 2 || races[0].ID != "late" || races[1].ID != "early" {
+		t.Errorf("races %+v, want late then early", races)
+	}
+	if history, _ := s.ListRatingHistory("u", 0); len(history) != 1 {
+		t.Errorf("%d rating changes, want 1", len(history))
+	}
+}
+
+func TestFileStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotype.json")
+	s := openFile(t, path)
+	fill(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s = openFile(t, path)
+	defer s.Close()
+	check(t, s)
+}
+
+// Changes not in a snapshot yet are in the log
+func TestFileStoreCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotype.json")
+	s := openFile(t, path)
+	fill(t, s)
+	s.log.Close() // no Close, no snapshot
+
+	// A change cut short by the crash
+	f, err := os.OpenFile(path+".log", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":5,"user":{"id":"v","na`)
+	f.Close()
+
+	s = openFile(t, path)
+	defer s.Close()
+	check(t, s)
+	if _, err := s.GetUser("v"); err != ErrNotFound {
+		t.Errorf("half written user loaded: %v", err)
+	}
+}
+
+// A log left behind by a crash after the snapshot was written is not
+// applied twice
+func TestFileStoreStaleLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gotype.json")
+	s := openFile(t, path)
+	fill(t, s)
+	log, err := os.ReadFile(path + ".log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".log", log, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openFile(t, path)
+	defer s.Close()
+	check(t, s)
+}
+
+func TestSaveRaceOrder(t *testing.T) {
+	s := NewMemoryStore()
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, r := range []Race{
+		{ID: "b", FinishedAt: at.Add(2 * time.Minute)},
+		{ID: "a", FinishedAt: at.Add(time.Minute)},
+		{ID: "c", FinishedAt: at.Add(3 * time.Minute)},
+		{ID: "a", FinishedAt: at.Add(4 * time.Minute), RoomID: "x"}, // updated, now the latest
+		{ID: "b", FinishedAt: at.Add(2 * time.Minute), RoomID: "y"}, // updated in place
+	} {
+		s.SaveRace(r)
+	}
+	races, _ := s.ListRaces(RaceFilter{})
+	var ids string
+	for _, r := range races {
+		ids += r.ID
+	}
+	if ids != "acb" || races[0].RoomID != "x" || races[2].RoomID != "y" {
+		t.Errorf("races %q (%+v), want a, c, b with the updates", ids, races)
+	}
+}
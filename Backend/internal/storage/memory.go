@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps everything in memory. Nothing survives a restart, it
+// is meant for tests and local development.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	users map[string]User
+	rooms map[string]Room
+	races []Race // oldest first, by finish time
+
+	raceIndex map[string]int // position of every race in races by ID
+
+	ratings       map[string]Rating
+	ratingHistory map[string][]RatingChange // by user, oldest first
+
+	typingStats map[string]TypingStats
+	replays     map[string]Replay // by race
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users: make(map[string]User),
+		rooms: make(map[string]Room),
+
+		raceIndex: make(map[string]int),
+
+		ratings:       make(map[string]Rating),
+		ratingHistory: make(map[string][]RatingChange),
+
+		typingStats: make(map[string]TypingStats),
+		replays:     make(map[string]Replay),
+	}
+}
+
+func (s *MemoryStore) UpsertUser(u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.users[u.ID]; ok && !existing.CreatedAt.IsZero() {
+		u.CreatedAt = existing.CreatedAt
+	}
+	s.users[u.ID] = u
+	return nil
+}
+
+func (s *MemoryStore) GetUser(id string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (s *MemoryStore) SaveRoom(r Room) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rooms[r.ID] = r
+	return nil
+}
+
+func (s *MemoryStore) GetRoom(id string) (Room, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.rooms[id]
+	if !ok {
+		return Room{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) SaveRace(r Race) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insertRace(r)
+	return nil
+}
+
+// insertRace puts r at its place in races, in the place of the race with
+// the same ID. Races are mostly saved as they finish, so r usually goes
+// at the end.
+func (s *MemoryStore) insertRace(r Race) {
+	if i, ok := s.raceIndex[r.ID]; ok {
+		if s.races[i].FinishedAt.Equal(r.FinishedAt) {
+			s.races[i] = r
+			return
+		}
+		s.races = slices.Delete(s.races, i, i+1)
+		s.reindexRaces(i)
+	}
+	i := sort.Search(len(s.races), func(i int) bool {
+		return s.races[i].FinishedAt.After(r.FinishedAt)
+	})
+	s.races = slices.Insert(s.races, i, r)
+	s.reindexRaces(i)
+}
+
+// setRaces replaces all the races, in any order
+func (s *MemoryStore) setRaces(races []Race) {
+	s.races = s.races[:0]
+	s.raceIndex = make(map[string]int, len(races))
+	sort.SliceStable(races, func(i, j int) bool {
+		return races[i].FinishedAt.Before(races[j].FinishedAt)
+	})
+	for _, r := range races {
+		if i, ok := s.raceIndex[r.ID]; ok {
+			s.races[i] = r
+			continue
+		}
+		s.raceIndex[r.ID] = len(s.races)
+		s.races = append(s.races, r)
+	}
+}
+
+// reindexRaces updates the index of the races from position from on
+func (s *MemoryStore) reindexRaces(from int) {
+	for i := from; i < len(s.races); i++ {
+		s.raceIndex[s.races[i].ID] = i
+	}
+}
+
+func (s *MemoryStore) ListRaces(f RaceFilter) ([]Race, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return filterRaces(s.races, f), nil
+}
+
+func (s *MemoryStore) GetRating(userID string) (Rating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.ratings[userID]
+	if !ok {
+		return Rating{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) SaveRatings(ratings []Rating, changes []RatingChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range ratings {
+		s.ratings[r.UserID] = r
+	}
+	for _, c := range changes {
+		s.ratingHistory[c.UserID] = append(s.ratingHistory[c.UserID], c)
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListRatingHistory(userID string, limit int) ([]RatingChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history := s.ratingHistory[userID]
+	out := make([]RatingChange, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		out = append(out, history[i])
+	}
+	return paginate(out, 0, limit), nil
+}
+
+func (s *MemoryStore) GetTypingStats(userID string) (TypingStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.typingStats[userID]
+	if !ok {
+		return TypingStats{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (s *MemoryStore) SaveTypingStats(t TypingStats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typingStats[t.UserID] = t
+	return nil
+}
+
+func (s *MemoryStore) SaveReplay(r Replay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replays[r.RaceID] = r
+	return nil
+}
+
+func (s *MemoryStore) GetReplay(raceID string) (Replay, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.replays[raceID]
+	if !ok {
+		return Replay{}, ErrNotFound
+	}
+	return r, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,282 @@
+// Package storage persists users, rooms and race results so they survive a
+// restart. Store is the abstraction the rest of the backend talks to, with
+// an in-memory implementation (tests / local dev) and a file backed one.
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("storage: not found")
+
+// User is an account created from the Google OAuth userinfo
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Picture   string    `json:"picture"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Room is the metadata of a hub
+type Room struct {
+	ID        string    `json:"id"`
+	HostID    string    `json:"host_id"`
+	Mode      string    `json:"mode"`
+	Language  string    `json:"language"`
+	Private   bool      `json:"private"`
+	CreatedAt time.Time `json:"created_at"`
+	ClosedAt  time.Time `json:"closed_at,omitempty"`
+
+	CloseReason string `json:"close_reason,omitempty"`
+}
+
+// Race is one finished round with the result of every participant
+type Race struct {
+	ID         string    `json:"id"`
+	RoomID     string    `json:"room_id"`
+	Round      int       `json:"round"`
+	Seed       string    `json:"seed"`
+	Text       string    `json:"text"`
+	WordCount  int       `json:"word_count"`
+	Duration   int       `json:"duration,omitempty"` // seconds of a timed race
+	Mode       string    `json:"mode"`
+	QuoteID    string    `json:"quote_id,omitempty"`   // corpus quote of a quote race
+	SnippetID  string    `json:"snippet_id,omitempty"` // code snippet of a code race
+	Language   string    `json:"language"`
+	Verified   bool      `json:"verified"`           // results computed by the server race engine
+	Unranked   bool      `json:"unranked,omitempty"` // kept off the leaderboard and the ratings (custom text, ghost races)
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Results    []Result  `json:"results"`
+}
+
+// Result returns the result of a participant
+func (r Race) Result(userID string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.UserID == userID {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// HasParticipant tells if the user took part in the race
+func (r Race) HasParticipant(userID string) bool {
+	_, ok := r.Result(userID)
+	return ok
+}
+
+// Result is the outcome of a race for a single player
+type Result struct {
+	UserID   string  `json:"user_id"`
+	Name     string  `json:"name"`
+	Place    int     `json:"place"`
+	WPM      float64 `json:"wpm"`
+	Accuracy float64 `json:"accuracy"`
+	TimeMs   int64   `json:"time_ms"`
+	Finished bool    `json:"finished"`
+	Flagged  bool    `json:"flagged,omitempty"` // flagged by the anti-cheat
+	Team     int     `json:"team,omitempty"`    // team in a team race
+}
+
+// Rating is the current skill rating of a user
+type Rating struct {
+	UserID    string    `json:"user_id"`
+	Rating    float64   `json:"rating"`
+	Races     int       `json:"races"` // number of rated races
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RatingChange is one entry of a user's rating history
+type RatingChange struct {
+	UserID       string    `json:"user_id"`
+	RaceID       string    `json:"race_id"`
+	Before       float64   `json:"before"`
+	After        float64   `json:"after"`
+	Delta        float64   `json:"delta"`
+	Place        int       `json:"place"`
+	Participants int       `json:"participants"`
+	At           time.Time `json:"at"`
+}
+
+// TypingStats is the keystroke telemetry of a user aggregated over all the
+// races it was recorded for
+type TypingStats struct {
+	UserID     string                `json:"user_id"`
+	Races      int                   `json:"races"`
+	Keystrokes int                   `json:"keystrokes"`
+	Keys       map[string]KeyStat    `json:"keys"`    // by expected character
+	Bigrams    map[string]BigramStat `json:"bigrams"` // by the two expected characters
+	// Sums over the races with enough keystrokes to measure consistency
+	ConsistencyRaces int       `json:"consistency_races"`
+	ConsistencySum   float64   `json:"consistency_sum"`
+	VarianceSum      float64   `json:"variance_sum"` // per-race variance of the WPM
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// KeyStat counts the presses of one expected character
+type KeyStat struct {
+	Presses   int   `json:"presses"`
+	Errors    int   `json:"errors"`
+	Timed     int   `json:"timed"`      // presses with a measured latency
+	LatencyMs int64 `json:"latency_ms"` // total latency of the timed presses
+}
+
+// BigramStat is the total latency of typing the second character of a
+// bigram right after the first one
+type BigramStat struct {
+	Count     int   `json:"count"`
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// Replay is the recorded progress of every participant of a race. It can
+// be downloaded or raced against as a ghost.
+type Replay struct {
+	RaceID    string        `json:"race_id"`
+	Text      string        `json:"text"`
+	Mode      string        `json:"mode"`
+	Language  string        `json:"language"`
+	StartedAt time.Time     `json:"started_at"`
+	Tracks    []ReplayTrack `json:"tracks"`
+}
+
+// ReplayTrack is the progress stream of one player. Every frame is a
+// [ms, pos, wpm×10] triple where ms is the time since the previous frame
+// (the race start for the first one), see package replay.
+type ReplayTrack struct {
+	UserID   string     `json:"user_id"`
+	Name     string     `json:"name"`
+	Finished bool       `json:"finished"`
+	Frames   [][3]int64 `json:"frames"`
+}
+
+// Track returns the track of a participant
+func (r Replay) Track(userID string) (ReplayTrack, bool) {
+	for _, t := range r.Tracks {
+		if t.UserID == userID {
+			return t, true
+		}
+	}
+	return ReplayTrack{}, false
+}
+
+// RaceFilter narrows down ListRaces. Zero values mean "no filter".
+type RaceFilter struct {
+	RoomID       string
+	UserID       string // races the user took part in
+	Mode         string
+	Language     string
+	WordCount    int
+	Duration     int
+	VerifiedOnly bool
+	RankedOnly   bool
+	Since        time.Time
+	Limit        int
+	Offset       int
+}
+
+// Store is implemented by every storage backend. Implementations must be
+// safe for concurrent use, hubs write from their own goroutines.
+type Store interface {
+	// UpsertUser creates the user or updates its profile fields.
+	// CreatedAt of an existing user is kept.
+	UpsertUser(u User) error
+	GetUser(id string) (User, error)
+
+	SaveRoom(r Room) error
+	GetRoom(id string) (Room, error)
+
+	SaveRace(r Race) error
+	// ListRaces returns races matching the filter, newest first
+	ListRaces(f RaceFilter) ([]Race, error)
+
+	GetRating(userID string) (Rating, error)
+	// SaveRatings stores the new ratings and appends the changes to the
+	// rating history, both in one write
+	SaveRatings(ratings []Rating, changes []RatingChange) error
+	// ListRatingHistory returns the rating changes of a user, newest first.
+	// A limit of zero returns the whole history.
+	ListRatingHistory(userID string, limit int) ([]RatingChange, error)
+
+	GetTypingStats(userID string) (TypingStats, error)
+	SaveTypingStats(s TypingStats) error
+
+	SaveReplay(r Replay) error
+	GetReplay(raceID string) (Replay, error)
+
+	Close() error
+}
+
+// NewID returns a random identifier for records
+func NewID() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// Open returns a file backed store persisted at path, or an in-memory
+// store when path is empty.
+func Open(path string) (Store, error) {
+	if path == "" {
+		return NewMemoryStore(), nil
+	}
+	return NewFileStore(path)
+}
+
+// filterRaces applies f to races, which must be sorted oldest first. The
+// matches are returned newest first.
+func filterRaces(races []Race, f RaceFilter) []Race {
+	out := make([]Race, 0)
+	for i := len(races) - 1; i >= 0; i-- {
+		r := races[i]
+		if f.RoomID != "" && r.RoomID != f.RoomID {
+			continue
+		}
+		if f.UserID != "" && !r.HasParticipant(f.UserID) {
+			continue
+		}
+		if f.Mode != "" && r.Mode != f.Mode {
+			continue
+		}
+		if f.Language != "" && r.Language != f.Language {
+			continue
+		}
+		if f.WordCount != 0 && r.WordCount != f.WordCount {
+			continue
+		}
+		if f.Duration != 0 && r.Duration != f.Duration {
+			continue
+		}
+		if f.VerifiedOnly && !r.Verified {
+			continue
+		}
+		if f.RankedOnly && r.Unranked {
+			continue
+		}
+		if !f.Since.IsZero() && r.FinishedAt.Before(f.Since) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return paginate(out, f.Offset, f.Limit)
+}
+
+func paginate[T any](items []T, offset, limit int) []T {
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
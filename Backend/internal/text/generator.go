@@ -0,0 +1,131 @@
+// Package text generates the race texts. Texts are built on the server so
+// a client can never decide what the rest of the room has to type.
+package text
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"unicode/utf16"
+)
+
+// DefaultLanguage is the language of WordBank
+const DefaultLanguage = "en"
+
+// Word count limits for a generated text
+const (
+	DefaultWordCount = 25
+	MinWordCount     = 5
+	MaxWordCount     = 200
+)
+
+// Generator is a deterministic pseudo random word picker. It is a port of
+// createSeededRng in the frontend gameLogic.js (string hash + LCG), so the
+// same seed yields the same words on the server and in the browser.
+type Generator struct {
+	state int32
+	words []string
+}
+
+// NewGenerator returns a generator over WordBank seeded with seed
+func NewGenerator(seed string) *Generator {
+	return newGenerator(seed, WordBank)
+}
+
+// NewLanguageGenerator returns a generator over the words of lang the
+// keyboard layout types (see Language.WordsFor), English when the language
+// is unknown
+func NewLanguageGenerator(seed, lang, layout string) *Generator {
+	if l, ok := Lookup(lang); ok {
+		return newGenerator(seed, l.WordsFor(layout))
+	}
+	return NewGenerator(seed)
+}
+
+func newGenerator(seed string, words []string) *Generator {
+	var h int32
+	// JS charCodeAt works on UTF-16 code units
+	for _, c := range utf16.Encode([]rune(seed)) {
+		h = (h << 5) - h + int32(c)
+	}
+	return &Generator{state: h, words: words}
+}
+
+// next returns a number in [0, 1]
+func (g *Generator) next() float64 {
+	g.state = g.state*1664525 + 1013904223
+	return float64(uint32(g.state)) / 0xffffffff
+}
+
+// Words picks count words
+func (g *Generator) Words(count int) []string {
+	out := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		idx := int(g.next() * float64(len(g.words)))
+		if idx >= len(g.words) {
+			idx = len(g.words) - 1
+		}
+		out = append(out, g.words[idx])
+	}
+	return out
+}
+
+// Text returns count words joined with single spaces
+func (g *Generator) Text(count int) string {
+	return strings.Join(g.Words(count), " ")
+}
+
+// GenerateSeeded returns the text generateTextSeeded(seed, wordCount)
+// produces in the frontend. The word count is clamped to the allowed range.
+func GenerateSeeded(seed string, wordCount int) string {
+	return NewGenerator(seed).Text(ClampWordCount(wordCount))
+}
+
+// GenerateLanguage returns a seeded text of wordCount words of lang typed
+// on layout. In English it is the text GenerateSeeded returns.
+func GenerateLanguage(seed, lang, layout string, wordCount int) string {
+	return NewLanguageGenerator(seed, lang, layout).Text(ClampWordCount(wordCount))
+}
+
+// TimedWordsPerSecond is how many words a timed text holds per second of
+// the race: enough for 240 WPM, nobody runs out of text
+const TimedWordsPerSecond = 4
+
+// GenerateTimed returns a seeded text of lang typed on layout, long enough
+// for a timed race of the given number of seconds. It is not clamped to
+// MaxWordCount.
+func GenerateTimed(seed, lang, layout string, seconds int) string {
+	return NewLanguageGenerator(seed, lang, layout).Text(seconds * TimedWordsPerSecond)
+}
+
+// Generate returns a text with a random seed
+func Generate(wordCount int) string {
+	return GenerateSeeded(NewSeed(), wordCount)
+}
+
+// NewSeed returns a random seed
+func NewSeed() string {
+	data := make([]byte, 8)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// IsSupportedLanguage tells if texts can be generated in the language
+func IsSupportedLanguage(lang string) bool {
+	_, ok := Lookup(lang)
+	return ok
+}
+
+// ClampWordCount keeps a requested word count inside the allowed limits,
+// zero or negative means the default.
+func ClampWordCount(n int) int {
+	switch {
+	case n <= 0:
+		return DefaultWordCount
+	case n < MinWordCount:
+		return MinWordCount
+	case n > MaxWordCount:
+		return MaxWordCount
+	}
+	return n
+}
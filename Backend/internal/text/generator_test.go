@@ -0,0 +1,38 @@
+package text
+
+import "testing"
+
+// TestSeededParity pins GenerateSeeded to generateTextSeeded in the
+// frontend gameLogic.js. The wanted texts were produced by the JS function,
+// if the Go port (or WordBank) drifts the browser and the server no longer
+// agree on the text of a seed.
+func TestSeededParity(t *testing.T) {
+	tests := []struct {
+		seed string
+		want string
+	}{
+		{"", "know other remember family any above how feel"},
+		{"abc", "car start when how at mark next feel"},
+		{"room-1#1", "mark west group say paper open hour after"},
+		{"नेपाल#3", "present number you if remember hold other second"}, // multi-byte runes
+		{"😀", "back toward hand hard problem small play its"},           // surrogate pair in UTF-16
+	}
+	for _, tt := range tests {
+		if got := NewGenerator(tt.seed).Text(8); got != tt.want {
+			t.Errorf("seed %q: got %q, want %q", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateSeededDeterministic(t *testing.T) {
+	a := GenerateSeeded("seed", 50)
+	if b := GenerateSeeded("seed", 50); a != b {
+		t.Errorf("same seed gave different texts:\n%s\n%s", a, b)
+	}
+	if c := GenerateSeeded("other", 50); a == c {
+		t.Errorf("different seeds gave the same text %q", a)
+	}
+	if got := GenerateLanguage("seed", DefaultLanguage, "", 50); got != a {
+		t.Errorf("GenerateLanguage(en) = %q, want the GenerateSeeded text %q", got, a)
+	}
+}
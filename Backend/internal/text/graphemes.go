@@ -0,0 +1,65 @@
+package text
+
+import "unicode"
+
+const zeroWidthJoiner = '\u200d'
+
+// Viramas of the Indic scripts: a virama followed by a consonant forms a
+// conjunct, which is typed and read as one character
+var viramas = map[rune]bool{
+	'\u094d': true, // Devanagari
+	'\u09cd': true, // Bengali
+	'\u0a4d': true, // Gurmukhi
+	'\u0acd': true, // Gujarati
+	'\u0b4d': true, // Oriya
+	'\u0bcd': true, // Tamil
+	'\u0c4d': true, // Telugu
+	'\u0ccd': true, // Kannada
+	'\u0d4d': true, // Malayalam
+}
+
+// extends tells if r continues the grapheme cluster prev is part of. This
+// covers what typing texts contain (combining marks, Indic vowel signs and
+// conjuncts, ZWJ emoji sequences, skin tones, CRLF), a subset of the
+// Unicode extended grapheme cluster rules.
+func extends(prev, r rune) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case r == zeroWidthJoiner, prev == zeroWidthJoiner:
+		return true
+	case unicode.Is(unicode.M, r):
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // emoji skin tone modifiers
+		return true
+	case viramas[prev] && unicode.IsLetter(r):
+		return true
+	}
+	return false
+}
+
+// Graphemes returns the number of user-perceived characters of s
+func Graphemes(s string) int {
+	counts := GraphemePrefixes([]rune(s))
+	return counts[len(counts)-1]
+}
+
+// GraphemePrefixes returns, for every rune offset i of runes (0 to
+// len(runes) included), the number of complete grapheme clusters in
+// runes[:i]. A cluster cut in half by i doesn't count yet.
+func GraphemePrefixes(runes []rune) []int {
+	counts := make([]int, len(runes)+1)
+	starts := 0
+	for i := 0; i <= len(runes); i++ {
+		boundary := i == 0 || i == len(runes) || !extends(runes[i-1], runes[i])
+		if boundary {
+			counts[i] = starts
+		} else {
+			counts[i] = starts - 1
+		}
+		if i < len(runes) && boundary {
+			starts++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,51 @@
+package text
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{"empty", "", 0},
+		{"ascii", "abc", 3},
+		{"precomposed accent", "café", 4},
+		{"combining accent", "cafe\u0301", 4},
+		{"several combining marks", "a\u0301\u0302\u0303b", 2},
+		{"devanagari conjunct and vowel sign", "नमस्ते", 3},
+		{"devanagari conjunct", "क्षा", 1},
+		{"emoji", "hi 👋", 4},
+		{"skin tone", "👍🏽", 1},
+		{"zwj family", "👨\u200d👩\u200d👧", 1},
+		{"zwj sequences side by side", "👨\u200d💻👩\u200d🔬", 2},
+		{"crlf", "a\r\nb", 3},
+	}
+	for _, tt := range tests {
+		if got := Graphemes(tt.s); got != tt.want {
+			t.Errorf("%s: Graphemes(%q) = %d, want %d", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestGraphemePrefixes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []int
+	}{
+		{"empty", "", []int{0}},
+		{"ascii", "ab", []int{0, 1, 2}},
+		{"combining accent cut in half", "e\u0301x", []int{0, 0, 1, 2}},
+		{"skin tone cut in half", "👍🏽!", []int{0, 0, 1, 2}},
+		{"zwj sequence", "a👨\u200d👩", []int{0, 1, 1, 1, 2}},
+	}
+	for _, tt := range tests {
+		if got := GraphemePrefixes([]rune(tt.s)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: GraphemePrefixes(%q) = %v, want %v", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
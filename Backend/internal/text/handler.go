@@ -0,0 +1,15 @@
+package text
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LanguagesHandler serves GET /api/languages, the languages a room can
+// race in
+func LanguagesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"languages": Languages()})
+	}
+}
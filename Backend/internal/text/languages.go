@@ -0,0 +1,136 @@
+package text
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Language is a language texts can be generated in
+type Language struct {
+	Code    string              `json:"code"`    // ISO 639-1 code
+	Name    string              `json:"name"`    // name in the language itself
+	Layouts []Layout            `json:"layouts"` // keyboard layouts it is typed on, the default first
+	Script  *unicode.RangeTable `json:"-"`       // script the language is written in
+	Words   []string            `json:"-"`       // word list seeded texts pick from
+}
+
+// languages is the registry of supported languages, by code
+var languages = map[string]Language{
+	"en": {Code: "en", Name: "English", Layouts: layoutsOf("us"), Script: unicode.Latin, Words: WordBank},
+	"de": {Code: "de", Name: "Deutsch", Layouts: layoutsOf("de", "us"), Script: unicode.Latin, Words: germanWords},
+	"es": {Code: "es", Name: "Español", Layouts: layoutsOf("es", "us"), Script: unicode.Latin, Words: spanishWords},
+	"ne": {Code: "ne", Name: "नेपाली", Layouts: layoutsOf("ne-traditional", "ne-romanized"), Script: unicode.Devanagari, Words: nepaliWords},
+}
+
+func layoutsOf(codes ...string) []Layout {
+	out := make([]Layout, len(codes))
+	for i, code := range codes {
+		out[i] = layouts[code]
+	}
+	return out
+}
+
+// Lookup returns the language with the given code
+func Lookup(code string) (Language, bool) {
+	l, ok := languages[code]
+	return l, ok
+}
+
+// Languages returns the supported languages sorted by code
+func Languages() []Language {
+	out := make([]Language, 0, len(languages))
+	for _, l := range languages {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// DefaultLayout returns the code of the layout the language is usually
+// typed on
+func (l Language) DefaultLayout() string {
+	return l.Layouts[0].Code
+}
+
+// HasLayout tells if the language can be typed on the layout
+func (l Language) HasLayout(code string) bool {
+	for _, layout := range l.Layouts {
+		if layout.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// WordsFor returns the words of the language the layout types. All the
+// words are returned for a layout the language isn't typed on, or when
+// the layout can't type any of them.
+func (l Language) WordsFor(layout string) []string {
+	if !l.HasLayout(layout) {
+		return l.Words
+	}
+	kb, _ := LookupLayout(layout)
+	words := make([]string, 0, len(l.Words))
+	for _, w := range l.Words {
+		if kb.Types(w) {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return l.Words
+	}
+	return words
+}
+
+// MatchesScript tells if s is written in the script of the language.
+// Characters shared by all scripts (digits, punctuation, spaces, combining
+// accents) are accepted in every language.
+func (l Language) MatchesScript(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Common, unicode.Inherited) {
+			continue
+		}
+		if !unicode.Is(l.Script, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Common German words, umlauts and ß included
+var germanWords = []string{
+	"der", "die", "das", "und", "sein", "in", "ein", "zu", "haben", "ich",
+	"werden", "sie", "von", "nicht", "mit", "es", "sich", "auch", "auf", "für",
+	"an", "er", "so", "dass", "können", "dies", "als", "ihr", "ja", "wie",
+	"bei", "oder", "wir", "aber", "dann", "man", "da", "noch", "nach", "was",
+	"also", "aus", "all", "wenn", "nur", "müssen", "sagen", "um", "über", "machen",
+	"kein", "Zeit", "gut", "mehr", "groß", "geben", "Jahr", "kommen", "schon", "wissen",
+	"sollen", "viel", "neu", "gehen", "heute", "Mann", "Tag", "immer", "Frau", "Kind",
+	"Haus", "Welt", "Leben", "Hand", "Stadt", "Weg", "Wasser", "Straße", "Tür", "Schule",
+	"früh", "spät", "schön", "klein", "weiß", "grün", "fünf", "zwölf", "hören", "sehen",
+	"Bäume", "Fuß", "Mädchen", "Brücke", "Gemüse", "Küche", "Größe", "natürlich", "während", "zurück",
+}
+
+// Common Spanish words, accents and ñ included
+var spanishWords = []string{
+	"el", "la", "de", "que", "y", "en", "un", "ser", "se", "no",
+	"haber", "por", "con", "su", "para", "como", "estar", "tener", "le", "lo",
+	"todo", "pero", "más", "hacer", "o", "poder", "decir", "este", "ir", "otro",
+	"ese", "si", "me", "ya", "ver", "porque", "dar", "cuando", "él", "muy",
+	"sin", "vez", "mucho", "saber", "qué", "sobre", "mi", "alguno", "mismo", "yo",
+	"también", "hasta", "año", "dos", "querer", "entre", "así", "primero", "desde", "grande",
+	"eso", "ni", "nos", "llegar", "pasar", "tiempo", "ella", "sí", "día", "uno",
+	"bien", "poco", "deber", "entonces", "poner", "cosa", "tanto", "hombre", "parecer", "nuestro",
+	"niño", "mañana", "corazón", "canción", "está", "después", "música", "país", "fácil", "último",
+	"señor", "camión", "árbol", "pequeño", "montaña", "mujer", "ciudad", "agua", "casa", "libro",
+}
+
+// Common Nepali words
+var nepaliWords = []string{
+	"म", "तिमी", "हामी", "उनी", "यो", "त्यो", "घर", "पानी", "खाना", "भात",
+	"नेपाल", "देश", "गाउँ", "शहर", "बाटो", "दिन", "रात", "बिहान", "साँझ", "समय",
+	"आमा", "बुबा", "दाजु", "दिदी", "भाइ", "बहिनी", "साथी", "मान्छे", "केटा", "केटी",
+	"राम्रो", "ठूलो", "सानो", "नयाँ", "पुरानो", "धेरै", "थोरै", "सबै", "अहिले", "भोलि",
+	"किताब", "विद्यालय", "पहाड", "हिमाल", "नदी", "फूल", "रुख", "आकाश", "माया", "खुसी",
+	"जानु", "आउनु", "खानु", "पढ्नु", "लेख्नु", "बोल्नु", "हेर्नु", "सुन्नु", "गर्नु", "बस्नु",
+}
@@ -0,0 +1,80 @@
+package text
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchesScript(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		s    string
+		want bool
+	}{
+		{"empty", "en", "", true},
+		{"latin", "en", "the quick fox", true},
+		{"latin with umlauts", "de", "Größe über Brücke", true},
+		{"latin with accents", "es", "mañana él canción", true},
+		{"combining accent", "es", "café", true},
+		{"digits and punctuation", "en", "1, 2... 3!", true},
+		{"devanagari", "ne", "नेपाल देश", true},
+		{"devanagari with digits and punctuation", "ne", "१२ घर, 3 दिन।", true},
+		{"devanagari in a latin language", "en", "hello नेपाल", false},
+		{"latin in devanagari", "ne", "नेपाल hello", false},
+		{"cyrillic", "de", "привет", false},
+		{"greek lookalike", "en", "hellο", false}, // Greek omicron
+	}
+	for _, tt := range tests {
+		lang, ok := Lookup(tt.lang)
+		if !ok {
+			t.Fatalf("%s: language %q not found", tt.name, tt.lang)
+		}
+		if got := lang.MatchesScript(tt.s); got != tt.want {
+			t.Errorf("%s: MatchesScript(%q) = %v, want %v", tt.name, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWordsFor(t *testing.T) {
+	de, _ := Lookup("de")
+	ne, _ := Lookup("ne")
+
+	if got := de.WordsFor("de"); !slices.Equal(got, de.Words) {
+		t.Errorf("WordsFor(de) has %d words, want all %d", len(got), len(de.Words))
+	}
+
+	us := de.WordsFor("us")
+	if len(us) == 0 || len(us) == len(de.Words) {
+		t.Fatalf("WordsFor(us) has %d words, want some of %d", len(us), len(de.Words))
+	}
+	for _, w := range us {
+		if slices.ContainsFunc([]rune(w), func(r rune) bool { return r > 127 }) {
+			t.Errorf("WordsFor(us) has %q, not typed on a US keyboard", w)
+		}
+	}
+	if !slices.Contains(us, "Haus") || slices.Contains(us, "Größe") {
+		t.Errorf("WordsFor(us) = %v, want Haus without Größe", us)
+	}
+
+	// A layout the language isn't typed on falls back to every word
+	if got := ne.WordsFor("us"); !slices.Equal(got, ne.Words) {
+		t.Errorf("ne WordsFor(us) has %d words, want all %d", len(got), len(ne.Words))
+	}
+	if got := ne.WordsFor(""); !slices.Equal(got, ne.Words) {
+		t.Errorf("ne WordsFor(\"\") has %d words, want all %d", len(got), len(ne.Words))
+	}
+}
+
+func TestDefaultLayout(t *testing.T) {
+	for _, lang := range Languages() {
+		if !lang.HasLayout(lang.DefaultLayout()) {
+			t.Errorf("%s: default layout %q not one of its layouts", lang.Code, lang.DefaultLayout())
+		}
+		for _, l := range lang.Layouts {
+			if _, ok := LookupLayout(l.Code); !ok || l.Name == "" {
+				t.Errorf("%s: layout %q not registered", lang.Code, l.Code)
+			}
+		}
+	}
+}
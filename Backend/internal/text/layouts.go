@@ -0,0 +1,40 @@
+package text
+
+import "unicode"
+
+// Layout is a keyboard layout texts can be generated for. Words with letters
+// the layout has no key for (umlauts on a US keyboard) are left out of the
+// texts generated for it, so nobody has to hunt for dead keys mid-race.
+type Layout struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	ASCII bool   `json:"-"` // only types the ASCII letters
+}
+
+// layouts is the registry of supported keyboard layouts, by code
+var layouts = map[string]Layout{
+	"us":             {Code: "us", Name: "US QWERTY", ASCII: true},
+	"de":             {Code: "de", Name: "Deutsch QWERTZ"},
+	"es":             {Code: "es", Name: "Español QWERTY"},
+	"ne-traditional": {Code: "ne-traditional", Name: "नेपाली परम्परागत"},
+	"ne-romanized":   {Code: "ne-romanized", Name: "नेपाली रोमनाइज्ड"},
+}
+
+// LookupLayout returns the keyboard layout with the given code
+func LookupLayout(code string) (Layout, bool) {
+	l, ok := layouts[code]
+	return l, ok
+}
+
+// Types tells if every letter of word has a key on the layout
+func (l Layout) Types(word string) bool {
+	if !l.ASCII {
+		return true
+	}
+	for _, r := range word {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package text
+
+// WordBank holds ~200 common English words. The order matters: it has to
+// stay identical to WORD_BANK in the frontend gameLogic.js so that seeded
+// texts generated on both sides match.
+var WordBank = []string{
+	"the", "be", "to", "of", "and", "a", "in", "that", "have", "it",
+	"for", "not", "on", "with", "as", "you", "do", "at", "this", "but",
+	"his", "by", "from", "they", "we", "say", "her", "she", "or", "an",
+	"will", "my", "one", "all", "would", "there", "their", "what", "so", "up",
+	"out", "if", "about", "who", "get", "which", "go", "me", "when", "make",
+	"can", "like", "time", "no", "just", "him", "know", "take", "people", "into",
+	"year", "your", "good", "some", "could", "them", "see", "other", "than", "then",
+	"now", "look", "only", "come", "its", "over", "think", "also", "back", "after",
+	"use", "two", "how", "our", "work", "first", "well", "way", "even", "new",
+	"want", "because", "any", "these", "give", "day", "most", "us", "great", "between",
+	"need", "large", "often", "hand", "high", "place", "hold", "turn", "such", "here",
+	"why", "move", "play", "small", "number", "off", "always", "next", "open", "seem",
+	"together", "white", "children", "begin", "got", "walk", "example", "ease", "paper", "group",
+	"music", "those", "both", "mark", "book", "letter", "until", "mile", "river", "car",
+	"feet", "care", "second", "enough", "plain", "girl", "usual", "young", "ready", "above",
+	"ever", "red", "list", "though", "feel", "talk", "bird", "soon", "body", "dog",
+	"family", "direct", "leave", "song", "door", "black", "short", "class", "wind", "question",
+	"happen", "complete", "ship", "area", "half", "rock", "order", "fire", "south", "problem",
+	"piece", "told", "knew", "pass", "since", "top", "whole", "king", "space", "heard",
+	"best", "hour", "better", "true", "during", "hundred", "five", "remember", "step", "early",
+	"west", "ground", "interest", "reach", "fast", "sing", "listen", "six", "table", "travel",
+	"less", "morning", "ten", "simple", "several", "toward", "night", "storm", "bright", "stand",
+	"change", "follow", "point", "write", "read", "earth", "light", "hard", "start", "run",
+	"ask", "home", "own", "call", "he", "must", "world", "person", "never", "present",
+	"many",
+}
@@ -2,14 +2,15 @@ package websockets
 
 type Action string
 const (
-	ActionDelete Action = "delete"
-	ActionJoin   Action = "join"
+	ActionDelete   Action = "delete"
+	ActionJoin     Action = "join"
+	ActionSpectate Action = "spectate" // watch the room without playing
 )
 
 //Function tells if the action is valid
 func IsValidAction(a Action) bool {
 	switch a {
-	case ActionJoin ,ActionDelete:
+	case ActionJoin ,ActionDelete, ActionSpectate:
 		return true
 	default:
 		return false
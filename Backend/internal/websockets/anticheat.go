@@ -0,0 +1,274 @@
+// This file scores every race for implausible typing. While a round runs
+// the hub watches each participant (how its verified position moves, what
+// the client claims) and when the race is over the watch, the final result
+// and the keystroke telemetry are turned into a suspicion score. Results
+// scoring FlagThreshold or more are flagged: they stay visible in the room
+// but never reach the leaderboards or the ratings.
+
+package websockets
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/race"
+)
+
+// CheatSignal names one kind of implausible behavior
+type CheatSignal string
+
+const (
+	SignalSuperhumanWPM    CheatSignal = "superhuman_wpm"    // faster than any human
+	SignalRoboticTiming    CheatSignal = "robotic_timing"    // keys or progress without variance
+	SignalPasteBurst       CheatSignal = "paste_burst"       // a lot of text in an instant
+	SignalBulkPaste        CheatSignal = "bulk_paste"        // most of the text in an instant
+	SignalProgressOverflow CheatSignal = "progress_overflow" // cursor sent past the end of the text
+	SignalForgedClaim      CheatSignal = "forged_claim"      // client claimed an impossible result
+)
+
+// Weight of each signal in the suspicion score
+var signalWeights = map[CheatSignal]float64{
+	SignalSuperhumanWPM:    1.0,
+	SignalRoboticTiming:    0.8,
+	SignalPasteBurst:       0.7,
+	SignalBulkPaste:        1.0,
+	SignalProgressOverflow: 0.5,
+	SignalForgedClaim:      0.5,
+}
+
+// Anti-cheat thresholds
+const (
+	FlagThreshold = 1.0 // a result scoring this much is flagged
+
+	MaxHumanWPM = 250.0 // above the sustained world records
+
+	// Paste: BurstChars or more verified characters within BurstWindow
+	// (40 chars in 500ms is ~960 WPM) or in one submission
+	BurstChars  = 40
+	BurstWindow = 500 * time.Millisecond
+
+	// Bulk paste: a burst of BulkMinChars or more covering BulkShare of
+	// the text, flagged on its own whatever the resulting WPM
+	BulkMinChars = 20
+	BulkShare    = 0.5
+
+	// Robotic timing: the inter-key gaps of at least RoboticMinGaps key
+	// presses, or the time per character of at least RoboticMinSubmissions
+	// submissions, vary by less than RoboticMaxStdDevMs
+	RoboticMinGaps        = 30
+	RoboticMinSubmissions = 15
+	RoboticMaxStdDevMs    = 4.0
+)
+
+// progressPoint is the verified position of a player at some time
+type progressPoint struct {
+	at  time.Time
+	pos int
+}
+
+// playerWatch is what the hub observed about a participant during a race
+type playerWatch struct {
+	points     []progressPoint
+	overflows  int     // progress with a cursor past the text
+	claimedWPM float64 // highest WPM the client claimed in game_finished
+}
+
+// Verdict is the anti-cheat outcome of a race for one participant
+type Verdict struct {
+	Score   float64
+	Signals []CheatSignal
+}
+
+// Flagged tells if the result must be excluded from rankings
+func (v Verdict) Flagged() bool {
+	return v.Score >= FlagThreshold
+}
+
+// add records a signal once
+func (v *Verdict) add(s CheatSignal) {
+	for _, existing := range v.Signals {
+		if existing == s {
+			return
+		}
+	}
+	v.Signals = append(v.Signals, s)
+	v.Score += signalWeights[s]
+}
+
+// String lists the signals, used in the event report
+func (v Verdict) String() string {
+	names := make([]string, len(v.Signals))
+	for i, s := range v.Signals {
+		names[i] = string(s)
+	}
+	return fmt.Sprintf("score %.1f: %s", v.Score, strings.Join(names, ", "))
+}
+
+// watch returns the watch of a participant, creating it
+func (h *Hub) watch(userID string) *playerWatch {
+	w, ok := h.watches[userID]
+	if !ok {
+		w = &playerWatch{}
+		h.watches[userID] = w
+	}
+	return w
+}
+
+// watchInput notes a progress submission before the engine clamps it
+func (h *Hub) watchInput(userID string, in race.Input) {
+	if in.Pos != nil && *in.Pos > h.race.Length() {
+		h.watch(userID).overflows++
+	}
+}
+
+// watchPosition notes the verified position of a player after a submission
+func (h *Hub) watchPosition(userID string, now time.Time) {
+	p := h.race.Player(userID)
+	if p == nil {
+		return
+	}
+	w := h.watch(userID)
+	w.points = append(w.points, progressPoint{at: now, pos: p.Position})
+}
+
+// watchClaim notes the result a client claims in game_finished. The claim
+// is never trusted, an impossible one is a signal.
+func (h *Hub) watchClaim(userID string, message Message) {
+	if h.race == nil || h.race.Player(userID) == nil {
+		return
+	}
+	var claim FinishPayload
+	if err := message.decodeContent(&claim); err != nil {
+		return
+	}
+	w := h.watch(userID)
+	w.claimedWPM = math.Max(w.claimedWPM, claim.WPM)
+}
+
+// judgeRace scores every result of the race that just ended, flags the
+// implausible ones and reports them
+func (h *Hub) judgeRace(results []RaceResult) {
+	for i := range results {
+		r := &results[i]
+		v := judge(*r, h.watches[r.ID], h.keystrokes[r.ID], h.race.StartTime(), h.race.Length())
+		if len(v.Signals) == 0 {
+			continue
+		}
+		r.Flagged = v.Flagged()
+
+		client := h.clientByID(r.ID)
+		if client == nil {
+			// Left before the end, report under its race name
+			client = &Clients{id: r.ID, name: r.Name}
+		}
+		msg := "Suspicious race result"
+		if r.Flagged {
+			msg = "Race result flagged as cheating"
+		}
+		h.EventReport(client, "[anticheat]", Cheat, msg, fmt.Errorf("%s", v))
+	}
+}
+
+// judge scores one participant of a race over a text of length characters
+func judge(r RaceResult, w *playerWatch, events []analytics.Keystroke, start time.Time, length int) Verdict {
+	var v Verdict
+	if r.WPM > MaxHumanWPM {
+		v.add(SignalSuperhumanWPM)
+	}
+	if w != nil {
+		if roboticProgress(w.points) {
+			v.add(SignalRoboticTiming)
+		}
+		if w.overflows > 0 {
+			v.add(SignalProgressOverflow)
+		}
+		if w.claimedWPM > MaxHumanWPM {
+			v.add(SignalForgedClaim)
+		}
+		burst := largestBurst(w.points, start)
+		if burst >= BurstChars {
+			v.add(SignalPasteBurst)
+		}
+		if length > 0 && burst >= BulkMinChars && float64(burst) >= BulkShare*float64(length) {
+			v.add(SignalBulkPaste)
+		}
+	}
+	if roboticTiming(events) {
+		v.add(SignalRoboticTiming)
+	}
+	return v
+}
+
+// largestBurst returns the most characters the verified position moved
+// within BurstWindow or in a single submission. Clients submit every word
+// they complete, so one submission moving far is a burst however long the
+// player waited before sending it. The race start counts as position 0.
+func largestBurst(points []progressPoint, start time.Time) int {
+	all := append([]progressPoint{{at: start, pos: 0}}, points...)
+	largest, from := 0, 0
+	for to := 1; to < len(all); to++ {
+		for all[to].at.Sub(all[from].at) > BurstWindow {
+			from++
+		}
+		largest = max(largest, all[to].pos-all[from].pos, all[to].pos-all[to-1].pos)
+	}
+	return largest
+}
+
+// roboticTiming tells if the inter-key gaps are too regular to be human.
+// Pauses (gaps above analytics.MaxLatencyMs) are left out.
+func roboticTiming(events []analytics.Keystroke) bool {
+	gaps := make([]float64, 0, len(events))
+	for i := 1; i < len(events); i++ {
+		gap := events[i].T - events[i-1].T
+		if gap >= 0 && gap <= analytics.MaxLatencyMs {
+			gaps = append(gaps, float64(gap))
+		}
+	}
+	return len(gaps) >= RoboticMinGaps && stdDev(gaps) < RoboticMaxStdDevMs
+}
+
+// roboticProgress tells if the verified position moves too regularly to be
+// human. It needs no telemetry: the time per character of every submission
+// after the first is compared, as observed by the server. Pauses (more than
+// analytics.MaxLatencyMs per character) are left out.
+func roboticProgress(points []progressPoint) bool {
+	perChar := make([]float64, 0, len(points))
+	for i := 1; i < len(points); i++ {
+		chars := points[i].pos - points[i-1].pos
+		if chars <= 0 {
+			continue
+		}
+		ms := float64(points[i].at.Sub(points[i-1].at)) / float64(time.Millisecond) / float64(chars)
+		if ms <= analytics.MaxLatencyMs {
+			perChar = append(perChar, ms)
+		}
+	}
+	return len(perChar) >= RoboticMinSubmissions && stdDev(perChar) < RoboticMaxStdDevMs
+}
+
+// stdDev returns the standard deviation of values
+func stdDev(values []float64) float64 {
+	var mean, variance float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+// clientByID returns a connection of the user, nil if it has none
+func (h *Hub) clientByID(userID string) *Clients {
+	for client := range h.clients {
+		if client.id == userID {
+			return client
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package websockets
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+)
+
+// points returns the verified positions of a player, one every step from
+// start
+func points(start time.Time, step time.Duration, positions ...int) []progressPoint {
+	var pts []progressPoint
+	for i, pos := range positions {
+		pts = append(pts, progressPoint{at: start.Add(time.Duration(i+1) * step), pos: pos})
+	}
+	return pts
+}
+
+// keystrokes returns n key presses gap ms apart, plus jitter ms every
+// other one
+func keystrokes(n int, gap, jitter int64) []analytics.Keystroke {
+	events := make([]analytics.Keystroke, n)
+	t := int64(0)
+	for i := range events {
+		events[i] = analytics.Keystroke{Key: "a", Expected: "a", T: t, Correct: true}
+		t += gap
+		if i%2 == 0 {
+			t += jitter
+		}
+	}
+	return events
+}
+
+func TestJudge(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	// A human at ~80 WPM: the 200 characters in 30s, a word of 8 or 12
+	// characters every 1.5s
+	human := make([]int, 20)
+	for i, pos := range human {
+		if i > 0 {
+			pos = human[i-1]
+		}
+		human[i] = pos + 8 + 4*(i%2)
+	}
+	// A bot at the same speed: 10 characters every 1.5s
+	bot := make([]int, 20)
+	for i := range bot {
+		bot[i] = (i + 1) * 10
+	}
+
+	tests := []struct {
+		name    string
+		result  RaceResult
+		watch   *playerWatch
+		events  []analytics.Keystroke
+		length  int // of the text, 200 when zero
+		signals []CheatSignal
+		flagged bool
+	}{
+		{
+			name:   "human",
+			result: RaceResult{WPM: 80},
+			watch:  &playerWatch{points: points(start, 1500*time.Millisecond, human...)},
+			events: keystrokes(100, 120, 40),
+		},
+		{
+			name:    "superhuman WPM alone",
+			result:  RaceResult{WPM: 300},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, human...)},
+			signals: []CheatSignal{SignalSuperhumanWPM},
+			flagged: true,
+		},
+		{
+			name:    "forged claim alone",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, human...), claimedWPM: 400},
+			signals: []CheatSignal{SignalForgedClaim},
+		},
+		{
+			name:    "overflow alone",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, human...), overflows: 2},
+			signals: []CheatSignal{SignalProgressOverflow},
+		},
+		{
+			name:    "forged claim and overflow",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, human...), overflows: 1, claimedWPM: 400},
+			signals: []CheatSignal{SignalProgressOverflow, SignalForgedClaim},
+			flagged: true,
+		},
+		{
+			name:    "small paste burst alone",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 10*time.Second, 10, 60, 70, 80)},
+			signals: []CheatSignal{SignalPasteBurst},
+		},
+		{
+			name:    "whole text in one submission, no telemetry",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 30*time.Second, 200)},
+			signals: []CheatSignal{SignalPasteBurst, SignalBulkPaste},
+			flagged: true,
+		},
+		{
+			name:    "half of a short text in one submission",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 10*time.Second, 25, 30)},
+			length:  40,
+			signals: []CheatSignal{SignalBulkPaste},
+			flagged: true,
+		},
+		{
+			name:    "robotic timing alone",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, human...)},
+			events:  keystrokes(100, 120, 0),
+			signals: []CheatSignal{SignalRoboticTiming},
+		},
+		{
+			name:    "robotic timing and paste burst",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 10*time.Second, 10, 60, 70, 80)},
+			events:  keystrokes(100, 120, 0),
+			signals: []CheatSignal{SignalPasteBurst, SignalRoboticTiming},
+			flagged: true,
+		},
+		{
+			name:    "robotic progress, no telemetry",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, bot...)},
+			signals: []CheatSignal{SignalRoboticTiming},
+		},
+		{
+			name:    "robotic progress and forged claim, no telemetry",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, bot...), claimedWPM: 400},
+			signals: []CheatSignal{SignalRoboticTiming, SignalForgedClaim},
+			flagged: true,
+		},
+		{
+			name:    "robotic progress and robotic keys",
+			result:  RaceResult{WPM: 80},
+			watch:   &playerWatch{points: points(start, 1500*time.Millisecond, bot...)},
+			events:  keystrokes(100, 120, 0),
+			signals: []CheatSignal{SignalRoboticTiming},
+		},
+		{
+			name:   "too few submissions to judge the progress",
+			result: RaceResult{WPM: 80},
+			watch:  &playerWatch{points: points(start, 1500*time.Millisecond, bot[:RoboticMinSubmissions]...)},
+		},
+		{
+			name:   "too few keys to judge the timing",
+			result: RaceResult{WPM: 80},
+			events: keystrokes(RoboticMinGaps, 120, 0),
+		},
+		{
+			name:    "no watch",
+			result:  RaceResult{WPM: 300},
+			signals: []CheatSignal{SignalSuperhumanWPM},
+			flagged: true,
+		},
+	}
+	for _, tt := range tests {
+		length := tt.length
+		if length == 0 {
+			length = 200
+		}
+		v := judge(tt.result, tt.watch, tt.events, start, length)
+		if !slices.Equal(v.Signals, tt.signals) {
+			t.Errorf("%s: signals %v, want %v", tt.name, v.Signals, tt.signals)
+		}
+		if v.Flagged() != tt.flagged {
+			t.Errorf("%s: flagged %v (score %.1f), want %v", tt.name, v.Flagged(), v.Score, tt.flagged)
+		}
+	}
+}
+
+func TestLargestBurst(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		points []progressPoint
+		want   int
+	}{
+		{"none", nil, 0},
+		{"from the start", points(start, 100*time.Millisecond, 50), 50},
+		{"inside the window", points(start, 200*time.Millisecond, 5, 10, 10, 10), 10},
+		{"split over the window", points(start, 400*time.Millisecond, 30, 60), 30},
+		{"slow typing", points(start, 600*time.Millisecond, 5, 10, 15), 5},
+		{"one submission after a pause", points(start, 30*time.Second, 10, 200), 190},
+	}
+	for _, tt := range tests {
+		if got := largestBurst(tt.points, start); got != tt.want {
+			t.Errorf("%s: largestBurst = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package websockets
 
 import (
-	"encoding/json"
 	"net"
 	"time"
 
@@ -11,9 +10,10 @@ import (
 
 // Player status constants
 const (
-	StatusIdle   = "idle"    // default state in lobby
-	StatusReady  = "ready"   // player has readied up
-	StatusInGame = "in_game" // player is currently in a game
+	StatusIdle       = "idle"       // default state in lobby
+	StatusReady      = "ready"      // player has readied up
+	StatusInGame     = "in_game"    // player is currently in a game
+	StatusSpectating = "spectating" // spectator, never ready nor in game
 )
 
 //Client represents one connected websocket user
@@ -21,8 +21,17 @@ type Clients struct{
 	hub 	   *Hub				// refrence to hub
 	connection *websocket.Conn //actual websocket connection
 	send       chan []byte// channel for outgoing messages
-	name 	   string 		   //name of the client
+	id         string          //stable user ID (google account id), used for routing
+	name 	   string 		   //display name of the client
 	status     string          // player status: "idle", "ready", "in_game"
+	joinedAt   time.Time       // when the connection was made, oldest player inherits the host role
+	spectator  bool            // watches the room without playing (action=spectate)
+	protocol   int             // protocol version negotiated at connect (?protocol=)
+	encoding   Encoding        // wire format negotiated at connect (subprotocol)
+
+	session     *clientSession // resumable session, set by the hub on register
+	resumeToken string         // session token the client asked to resume
+	lastSeq     uint64         // last sequence number the resuming client received
 }
 
 const (
@@ -37,7 +46,11 @@ func (c *Clients) ReadPump() {
 	logger.Logger.Info("[ReadPump] Connection started", "user", c.name)
 	defer func() {
 		logger.Logger.Warn("[ReadPump] Connection closing", "user", c.name)
-		c.hub.unregistered <- c
+		// A closed hub no longer reads from its channels
+		select {
+		case c.hub.unregistered <- c:
+		case <-c.hub.done:
+		}
 		c.connection.Close()
 	}()
 
@@ -66,17 +79,22 @@ func (c *Clients) ReadPump() {
 			break
 		}
 
-		var message Message
-		if err := json.Unmarshal(data, &message); err != nil {
-			logger.Logger.Error("[ReadPump] JSON unmarshal failed", "error", err)
-			c.hub.EventReport(c, "read", "error", "Error in json Unmarshal", err)
+		message, err := decodeMessage(c.encoding, data, c.protocol)
+		if err != nil {
+			logger.Logger.Error("[ReadPump] Decoding failed", "encoding", c.encoding.Subprotocol(), "error", err)
+			c.hub.EventReport(c, "read", "error", "Error in message decoding", err)
 			continue
 		}
-		message.Sender = c.name
+		message.Sender = c.id
+		message.SenderName = c.name
 		message.RoomId = c.hub.roomId
 		message.TimeStamp = time.Now()
 		logger.Logger.Debug("[ReadPump] Message forwarded to broadcast", "type", message.Type, "user", c.name)
-		c.hub.broadcast <- message
+		select {
+		case c.hub.broadcast <- message:
+		case <-c.hub.done:
+			return
+		}
 	}
 }
 
@@ -107,7 +125,9 @@ func (c *Clients) WritePump() {
 			// The old approach used NextWriter + batching loop which concatenated
 			// multiple JSON objects into a single frame without delimiters,
 			// causing JSON.parse failures on the client (messages silently lost).
-			if err := c.connection.WriteMessage(websocket.TextMessage, message); err != nil {
+			// The frame type follows the wire format: text for JSON, binary
+			// for MessagePack.
+			if err := c.connection.WriteMessage(c.encoding.FrameType(), message); err != nil {
 				c.hub.EventReport(c, "write", Error, "write error", err)
 				logger.Logger.Error("[WritePump] Write error", "user", c.name, "error", err)
 				return
@@ -0,0 +1,204 @@
+// This file implements the elimination mode. A match is a series of rounds
+// played back to back: when a round is over the last placed player is
+// eliminated and becomes a spectator, and after a short intermission the
+// next round starts by itself with a new text. Only the players of the
+// first round take part in the match, those who join later wait for the
+// next one. The last player standing wins and the room goes back to the
+// lobby.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// EliminationIntermission is the pause between two elimination rounds,
+// long enough to read the results
+const EliminationIntermission = 5 * time.Second
+
+// EliminatedPayload is the content of a player_eliminated message
+type EliminatedPayload struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Round     int    `json:"round"`     // elimination round, 1 for the first one
+	Place     int    `json:"place"`     // place in that round
+	Remaining int    `json:"remaining"` // players still in the match
+}
+
+// WinnerPayload is the content of an elimination_winner message
+type WinnerPayload struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Rounds int    `json:"rounds"`
+}
+
+// eliminate knocks the last placed player of the round out of the match.
+// It returns true while the match goes on, the next round is then
+// scheduled; otherwise the winner is announced.
+func (h *Hub) eliminate(results []RaceResult) bool {
+	h.eliminationRound++
+
+	// A round raced alone has nobody to eliminate, the player just won
+	if len(results) >= 2 {
+		loser := results[len(results)-1]
+		h.eliminated[loser.ID] = true
+		delete(h.survivors, loser.ID)
+		for client := range h.clients {
+			if client.id == loser.ID {
+				client.spectator = true
+				client.status = StatusSpectating
+			}
+		}
+		h.broadcastElimination(PlayerEliminatedMessage, EliminatedPayload{
+			ID:        loser.ID,
+			Name:      loser.Name,
+			Round:     h.eliminationRound,
+			Place:     loser.Place,
+			Remaining: h.survivorCount(),
+		})
+		logger.Logger.Info("[Elimination] Player eliminated",
+			"roomId", h.roomId,
+			"player", loser.ID,
+			"round", h.eliminationRound,
+			"remaining", h.survivorCount(),
+		)
+	}
+
+	if h.survivorCount() >= 2 {
+		h.scheduleNextRound()
+		return true
+	}
+
+	winner := h.survivor()
+	if winner == nil && len(results) > 0 && !h.eliminated[results[0].ID] {
+		// The survivor left during the round, it still won it
+		winner = &WinnerPayload{ID: results[0].ID, Name: results[0].Name}
+	}
+	h.endElimination(winner)
+	return false
+}
+
+// survivor returns the only player left in the match, nil if there is not
+// exactly one
+func (h *Hub) survivor() *WinnerPayload {
+	var winner *WinnerPayload
+	for client := range h.clients {
+		if !h.inMatch(client) {
+			continue
+		}
+		if winner != nil && winner.ID != client.id {
+			return nil
+		}
+		winner = &WinnerPayload{ID: client.id, Name: client.name}
+	}
+	return winner
+}
+
+// endElimination announces the winner of the match, if any, and gives the
+// eliminated players their seat back for the next match
+func (h *Hub) endElimination(winner *WinnerPayload) {
+	h.stopNextRoundTimer()
+	if winner != nil {
+		winner.Rounds = h.eliminationRound
+		h.broadcastElimination(EliminationWinnerMessage, *winner)
+		logger.Logger.Info("[Elimination] Match won",
+			"roomId", h.roomId,
+			"winner", winner.ID,
+			"rounds", winner.Rounds,
+		)
+	}
+
+	for client := range h.clients {
+		if h.eliminated[client.id] {
+			client.spectator = false
+			client.status = StatusIdle
+		}
+	}
+	h.eliminated = make(map[string]bool)
+	h.survivors = nil
+	h.eliminationRound = 0
+}
+
+// inMatch tells if a connection takes part in the rounds. Before the first
+// round of an elimination match (and outside of elimination mode) every
+// player does, afterwards only the survivors.
+func (h *Hub) inMatch(client *Clients) bool {
+	return !client.spectator && (h.survivors == nil || h.survivors[client.id])
+}
+
+// survivorCount returns the number of connections still in the match
+func (h *Hub) survivorCount() int {
+	n := 0
+	for client := range h.clients {
+		if h.inMatch(client) {
+			n++
+		}
+	}
+	return n
+}
+
+// applyElimination keeps an eliminated player spectating when it
+// reconnects during the match
+func (h *Hub) applyElimination(client *Clients) {
+	if h.eliminated[client.id] {
+		client.spectator = true
+		client.status = StatusSpectating
+	}
+}
+
+// scheduleNextRound starts the next elimination round after the
+// intermission. The timer only signals the Run loop.
+func (h *Hub) scheduleNextRound() {
+	h.stopNextRoundTimer()
+	round := h.round
+	h.nextRoundTimer = time.AfterFunc(EliminationIntermission, func() {
+		select {
+		case h.nextRounds <- round:
+		default:
+		}
+	})
+}
+
+// stopNextRoundTimer cancels a pending elimination round
+func (h *Hub) stopNextRoundTimer() {
+	if h.nextRoundTimer != nil {
+		h.nextRoundTimer.Stop()
+		h.nextRoundTimer = nil
+	}
+}
+
+// handleNextRound starts the elimination round that follows round. When
+// players left during the intermission the match may already be decided.
+func (h *Hub) handleNextRound(round int) {
+	if round != h.round || h.race != nil || h.state != RoomStateRacing {
+		return
+	}
+	h.nextRoundTimer = nil
+
+	if h.survivorCount() < 2 {
+		h.endElimination(h.survivor())
+		h.backToLobby()
+		return
+	}
+	h.startRound()
+	h.sendGameGo()
+}
+
+// broadcastElimination sends a player_eliminated or elimination_winner
+// message to the room
+func (h *Hub) broadcastElimination(msgType string, v any) {
+	content, err := encodeContent(v)
+	if err != nil {
+		logger.Logger.Error("[Elimination] Failed to encode message", "type", msgType, "error", err)
+		return
+	}
+	h.broadcastAll(Message{
+		Type:      msgType,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
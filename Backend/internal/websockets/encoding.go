@@ -0,0 +1,399 @@
+// This file implements the wire formats of the messages. JSON over text
+// frames is the default; a client that offers the gotype.msgpack
+// subprotocol when it connects gets MessagePack over binary frames instead.
+//
+// The MessagePack form of a message is smaller than the JSON one: the room
+// ID is left out (a connection belongs to one room), so are empty fields,
+// the timestamp is a Unix time in milliseconds and the content is the
+// payload itself, numbers as integers where they are whole. The content is
+// transcoded from the JSON the hub holds straight into MessagePack.
+
+package websockets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// WebSocket subprotocols selecting the wire format
+const (
+	SubprotocolJSON    = "gotype.json"
+	SubprotocolMsgpack = "gotype.msgpack"
+)
+
+// Subprotocols lists the subprotocols the server speaks, preferred first
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}
+
+// Encoding is a wire format of the messages
+type Encoding interface {
+	// Subprotocol returns the WebSocket subprotocol selecting the format
+	Subprotocol() string
+	// FrameType returns the WebSocket frame type messages are sent in
+	FrameType() int
+	// Encode encodes a message
+	Encode(msg Message) ([]byte, error)
+	// Decode decodes a message received from a client, strict rejects
+	// unknown fields
+	Decode(data []byte, strict bool) (Message, error)
+}
+
+// EncodingFor returns the encoding of a negotiated subprotocol, JSON when
+// the client asked for none
+func EncodingFor(subprotocol string) Encoding {
+	if subprotocol == SubprotocolMsgpack {
+		return msgpackEncoding{}
+	}
+	return jsonEncoding{}
+}
+
+// jsonEncoding sends messages as JSON in text frames
+type jsonEncoding struct{}
+
+func (jsonEncoding) Subprotocol() string { return SubprotocolJSON }
+func (jsonEncoding) FrameType() int      { return websocket.TextMessage }
+
+func (jsonEncoding) Encode(msg Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonEncoding) Decode(data []byte, strict bool) (Message, error) {
+	var msg Message
+	err := decodeJSON(data, &msg, strict)
+	return msg, err
+}
+
+// msgpackEncoding sends messages as MessagePack in binary frames
+type msgpackEncoding struct{}
+
+// msgpackMessage is the MessagePack form of a message a client sends
+type msgpackMessage struct {
+	Type       string `codec:"type"`
+	RoomId     string `codec:"room_id,omitempty"` // never sent by the server
+	Sender     string `codec:"sender,omitempty"`
+	SenderName string `codec:"sender_name,omitempty"`
+	Reciever   string `codec:"reciever,omitempty"`
+	Content    any    `codec:"content,omitempty"`
+	TimeStamp  int64  `codec:"timestamp,omitempty"` // Unix ms
+	Seq        uint64 `codec:"seq,omitempty"`
+}
+
+var msgpackHandle, msgpackStrictHandle = newMsgpackHandle(false), newMsgpackHandle(true)
+
+func newMsgpackHandle(strict bool) *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true // strings and bytes both decode to strings
+	h.MapType = reflect.TypeFor[map[string]any]()
+	h.ErrorIfNoField = strict
+	return h
+}
+
+func (msgpackEncoding) Subprotocol() string { return SubprotocolMsgpack }
+func (msgpackEncoding) FrameType() int      { return websocket.BinaryMessage }
+
+func (msgpackEncoding) Encode(msg Message) ([]byte, error) {
+	fields := [...]struct {
+		key, value string
+	}{
+		{"type", msg.Type},
+		{"sender", msg.Sender},
+		{"sender_name", msg.SenderName},
+		{"reciever", msg.Reciever},
+	}
+	n := 0
+	for _, f := range fields {
+		if f.value != "" {
+			n++
+		}
+	}
+	hasTime, hasContent, hasSeq := !msg.TimeStamp.IsZero(), len(msg.Content) > 0, msg.Seq != 0
+	for _, has := range []bool{hasTime, hasContent, hasSeq} {
+		if has {
+			n++
+		}
+	}
+
+	// Written by hand, every field is a string or an integer
+	out := make([]byte, 0, 64+len(msg.Type)+len(msg.Sender)+len(msg.SenderName)+len(msg.Reciever)+len(msg.Content))
+	out = append(out, 0x80|byte(n))
+	for _, f := range fields {
+		if f.value != "" {
+			out = appendStr(appendStr(out, f.key), f.value)
+		}
+	}
+	if hasContent {
+		var err error
+		if out, err = appendMsgpack(appendStr(out, "content"), msg.Content); err != nil {
+			return nil, err
+		}
+	}
+	if hasTime {
+		out = appendInt(appendStr(out, "timestamp"), msg.TimeStamp.UnixMilli())
+	}
+	if hasSeq {
+		out = appendInt(appendStr(out, "seq"), int64(msg.Seq))
+	}
+	return out, nil
+}
+
+func (msgpackEncoding) Decode(data []byte, strict bool) (Message, error) {
+	h := msgpackHandle
+	if strict {
+		h = msgpackStrictHandle
+	}
+	var wire msgpackMessage
+	if err := codec.NewDecoderBytes(data, h).Decode(&wire); err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{
+		Type:       wire.Type,
+		RoomId:     wire.RoomId,
+		Sender:     wire.Sender,
+		SenderName: wire.SenderName,
+		Reciever:   wire.Reciever,
+		Seq:        wire.Seq,
+	}
+	if wire.TimeStamp != 0 {
+		msg.TimeStamp = time.UnixMilli(wire.TimeStamp)
+	}
+	if wire.Content != nil {
+		content, err := json.Marshal(wire.Content)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Content = content
+	}
+	return msg, nil
+}
+
+// transcoder converts JSON into MessagePack without decoding it into Go
+// values first: whole numbers become integers, other numbers float32 when
+// that is exact, float64 otherwise
+type transcoder struct {
+	in  []byte
+	i   int
+	out []byte
+}
+
+var errBadJSON = errors.New("invalid JSON content")
+
+// appendMsgpack appends the MessagePack form of the JSON value data to dst
+func appendMsgpack(dst, data []byte) ([]byte, error) {
+	t := transcoder{in: data, out: dst}
+	if err := t.value(); err != nil {
+		return dst, err
+	}
+	if t.skipSpace(); t.i != len(t.in) {
+		return dst, errBadJSON
+	}
+	return t.out, nil
+}
+
+func (t *transcoder) skipSpace() {
+	for t.i < len(t.in) {
+		switch t.in[t.i] {
+		case ' ', '\t', '\n', '\r':
+			t.i++
+		default:
+			return
+		}
+	}
+}
+
+// next skips whitespace and returns the next byte, 0 at the end
+func (t *transcoder) next() byte {
+	if t.skipSpace(); t.i < len(t.in) {
+		return t.in[t.i]
+	}
+	return 0
+}
+
+func (t *transcoder) literal(word string, code byte) error {
+	if !bytes.HasPrefix(t.in[t.i:], []byte(word)) {
+		return errBadJSON
+	}
+	t.i += len(word)
+	t.out = append(t.out, code)
+	return nil
+}
+
+func (t *transcoder) value() error {
+	switch c := t.next(); {
+	case c == '{':
+		return t.container('}', 0x80, 0xde, 0xdf, true)
+	case c == '[':
+		return t.container(']', 0x90, 0xdc, 0xdd, false)
+	case c == '"':
+		return t.str()
+	case c == 't':
+		return t.literal("true", 0xc3)
+	case c == 'f':
+		return t.literal("false", 0xc2)
+	case c == 'n':
+		return t.literal("null", 0xc0)
+	case c == '-' || c >= '0' && c <= '9':
+		return t.number()
+	}
+	return errBadJSON
+}
+
+// container transcodes an object or an array. The number of entries is
+// only known at the end: a 32 bit header is written first, then shrunk.
+func (t *transcoder) container(end, fix, code16, code32 byte, object bool) error {
+	t.i++
+	header := len(t.out)
+	t.out = append(t.out, code32, 0, 0, 0, 0)
+	n := 0
+	if t.next() == end {
+		t.i++
+	} else {
+		for {
+			if object {
+				if t.next() != '"' {
+					return errBadJSON
+				}
+				if err := t.str(); err != nil {
+					return err
+				}
+				if t.next() != ':' {
+					return errBadJSON
+				}
+				t.i++
+			}
+			if err := t.value(); err != nil {
+				return err
+			}
+			n++
+			c := t.next()
+			t.i++
+			if c == end {
+				break
+			}
+			if c != ',' {
+				return errBadJSON
+			}
+		}
+	}
+
+	size := 5
+	switch {
+	case n < 16:
+		t.out[header] = fix | byte(n)
+		size = 1
+	case n <= math.MaxUint16:
+		t.out[header] = code16
+		binary.BigEndian.PutUint16(t.out[header+1:], uint16(n))
+		size = 3
+	default:
+		binary.BigEndian.PutUint32(t.out[header+1:], uint32(n))
+	}
+	copy(t.out[header+size:], t.out[header+5:])
+	t.out = t.out[:len(t.out)-5+size]
+	return nil
+}
+
+func (t *transcoder) str() error {
+	start := t.i + 1
+	escaped := false
+	end := start
+	for ; end < len(t.in) && t.in[end] != '"'; end++ {
+		if t.in[end] == '\\' {
+			escaped = true
+			end++
+		}
+	}
+	if end >= len(t.in) {
+		return errBadJSON
+	}
+	t.i = end + 1
+
+	if !escaped {
+		t.out = appendStr(t.out, t.in[start:end])
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(t.in[start-1:end+1], &s); err != nil {
+		return err
+	}
+	t.out = appendStr(t.out, s)
+	return nil
+}
+
+// appendStr appends s as a MessagePack string
+func appendStr[S string | []byte](out []byte, s S) []byte {
+	switch n := len(s); {
+	case n < 32:
+		out = append(out, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		out = append(out, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		out = binary.BigEndian.AppendUint16(append(out, 0xda), uint16(n))
+	default:
+		out = binary.BigEndian.AppendUint32(append(out, 0xdb), uint32(n))
+	}
+	return append(out, s...)
+}
+
+func (t *transcoder) number() error {
+	start := t.i
+	whole := true
+	for ; t.i < len(t.in); t.i++ {
+		c := t.in[t.i]
+		if c == '.' || c == 'e' || c == 'E' || c == '+' {
+			whole = false
+		} else if c != '-' && (c < '0' || c > '9') {
+			break
+		}
+	}
+	text := string(t.in[start:t.i])
+	if whole {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			t.out = appendInt(t.out, n)
+			return nil
+		}
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return errBadJSON
+	}
+	if f32 := float32(f); float64(f32) == f {
+		t.out = binary.BigEndian.AppendUint32(append(t.out, 0xca), math.Float32bits(f32))
+	} else {
+		t.out = binary.BigEndian.AppendUint64(append(t.out, 0xcb), math.Float64bits(f))
+	}
+	return nil
+}
+
+// appendInt appends n in the smallest MessagePack integer format
+func appendInt(out []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(out, byte(n))
+	case n >= -32 && n < 0:
+		return append(out, byte(n))
+	case n > 0 && n <= math.MaxUint8:
+		return append(out, 0xcc, byte(n))
+	case n > 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(out, 0xcd), uint16(n))
+	case n > 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(out, 0xce), uint32(n))
+	case n > 0:
+		return binary.BigEndian.AppendUint64(append(out, 0xcf), uint64(n))
+	case n >= math.MinInt8:
+		return append(out, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(out, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(out, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(out, 0xd3), uint64(n))
+}
@@ -0,0 +1,121 @@
+package websockets
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/race"
+)
+
+// progressMessage returns a player_progress message as the hub broadcasts
+// it during a race
+func progressMessage(tb testing.TB) Message {
+	tb.Helper()
+	content, err := encodeContent(race.Progress{
+		ID:       "109876543210987654321",
+		Name:     "Player One",
+		Pos:      142,
+		WPM:      87.5,
+		Accuracy: 97.25,
+		Chars:    139,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return Message{
+		Type:      PlayerProgress,
+		RoomId:    "3f2b9c1e-8d4a-4e6b-9a7c-5d1e2f3a4b5c",
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Date(2025, 6, 1, 12, 30, 45, 123456789, time.UTC),
+		Seq:       1042,
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	msg := progressMessage(t)
+	enc := EncodingFor(SubprotocolMsgpack)
+	data, err := enc.Encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := enc.Decode(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want, have race.Progress
+	if err := json.Unmarshal(msg.Content, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(got.Content, &have); err != nil {
+		t.Fatal(err)
+	}
+	if have != want {
+		t.Errorf("content = %+v, want %+v", have, want)
+	}
+	if got.Type != msg.Type || got.Seq != msg.Seq || !got.TimeStamp.Equal(msg.TimeStamp.Truncate(time.Millisecond)) {
+		t.Errorf("decoded %+v, want %+v", got, msg)
+	}
+}
+
+// BenchmarkBroadcastProgress encodes a player_progress message for every
+// client of a full room, the work of one broadcast, in each wire format
+func BenchmarkBroadcastProgress(b *testing.B) {
+	const roomSize = 8
+	msg := progressMessage(b)
+	for _, subprotocol := range []string{SubprotocolJSON, SubprotocolMsgpack} {
+		b.Run(subprotocol, func(b *testing.B) {
+			clients := make([]*Clients, roomSize)
+			for i := range clients {
+				clients[i] = &Clients{protocol: LatestProtocol, encoding: EncodingFor(subprotocol)}
+			}
+			size := 0
+			b.ReportAllocs()
+			for b.Loop() {
+				size = 0
+				for _, c := range clients {
+					size += len(c.encode(msg))
+				}
+			}
+			b.ReportMetric(float64(size), "bytes/broadcast")
+		})
+	}
+}
+
+// BenchmarkDecodeProgress decodes a player_progress message sent by a
+// client in each wire format
+func BenchmarkDecodeProgress(b *testing.B) {
+	content, _ := encodeContent(race.Input{Keys: "the"})
+	msg := Message{Type: PlayerProgress, Content: content}
+	for _, subprotocol := range []string{SubprotocolJSON, SubprotocolMsgpack} {
+		b.Run(subprotocol, func(b *testing.B) {
+			enc := EncodingFor(subprotocol)
+			data, err := enc.Encode(msg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+			for b.Loop() {
+				if _, err := decodeMessage(enc, data, LatestProtocol); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMsgpackSmallerThanJSON(t *testing.T) {
+	msg := progressMessage(t)
+	jsonData := encodeMessage(EncodingFor(SubprotocolJSON), msg)
+	msgpackData := encodeMessage(EncodingFor(SubprotocolMsgpack), msg)
+	if len(msgpackData) >= len(jsonData) {
+		t.Errorf("msgpack %d bytes, json %d bytes", len(msgpackData), len(jsonData))
+	}
+	if bytes.Contains(msgpackData, []byte(msg.RoomId)) {
+		t.Error("msgpack message repeats the room ID")
+	}
+}
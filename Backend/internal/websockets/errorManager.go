@@ -21,4 +21,5 @@ const (
 	Fatal 	Severity = "fatal"
 	Warning Severity = "warning"
 	Error 	Severity = "error"
+	Cheat 	Severity = "cheat" // implausible typing detected by the anti-cheat
 )
@@ -0,0 +1,205 @@
+// This file races a recorded player against the room. When the room has a
+// ghost configured, every round uses the text of the ghost's race and,
+// once the race starts, the hub replays the ghost's progress stream in real
+// time as player_progress messages. The ghost is not a participant: it is
+// never in the race results, the recorded races or the ratings. The race
+// itself is unranked, its text is one the players may have seen before.
+
+package websockets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/race"
+	"github.com/ManogyaDahal/GoType/internal/replay"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/ManogyaDahal/GoType/internal/text"
+)
+
+// GhostIDPrefix prefixes the user ID of a ghost in race messages, so the
+// ghost of a user never collides with the user racing live
+const GhostIDPrefix = "ghost:"
+
+// ghostRun is the ghost of the current round
+type ghostRun struct {
+	id       string
+	name     string
+	text     string
+	finished bool // the recorded player completed the text
+	frames   []replay.Frame
+}
+
+// ghostFrame is a frame due for broadcast, sent to the Run loop
+type ghostFrame struct {
+	round int
+	frame replay.Frame
+	last  bool
+}
+
+// resolveGhost checks that the ghost of the settings has a replay and pins
+// it to one race and track, so "best of" requests don't change between
+// rounds. The ghost's race decides the text, so word_count and language are
+// taken from it. userID is the user changing the settings, "me" stands
+// for it.
+func resolveGhost(store storage.Store, s *RoomSettings, userID string) error {
+	if s.Ghost == nil {
+		return nil
+	}
+	if s.Mode != ModeWords {
+		return fmt.Errorf("ghost races are only available in %s mode", ModeWords)
+	}
+	g := *s.Ghost
+	if g.UserID == "me" {
+		g.UserID = userID
+	}
+
+	var (
+		rep storage.Replay
+		err error
+	)
+	if g.RaceID == "" {
+		rep, err = replay.Best(store, g.UserID, string(ModeWords))
+	} else {
+		rep, err = store.GetReplay(g.RaceID)
+	}
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, replay.ErrNoReplay) {
+		return fmt.Errorf("no replay found for the ghost")
+	}
+	if err != nil {
+		return err
+	}
+	if rep.Mode != "" && rep.Mode != string(ModeWords) {
+		return fmt.Errorf("ghost races are only available in %s mode", ModeWords)
+	}
+
+	var (
+		track storage.ReplayTrack
+		ok    bool
+	)
+	if g.UserID == "" {
+		track, ok = replay.Fastest(rep)
+	} else {
+		track, ok = rep.Track(g.UserID)
+	}
+	if !ok {
+		return fmt.Errorf("no replay found for the ghost")
+	}
+
+	s.Ghost = &GhostSettings{UserID: track.UserID, RaceID: rep.RaceID, Name: track.Name}
+	s.WordCount = len(strings.Fields(rep.Text))
+	if lang, ok := text.Lookup(rep.Language); ok && rep.Language != s.Language {
+		s.Language = rep.Language
+		s.Layout = lang.DefaultLayout()
+	}
+	return nil
+}
+
+// loadGhost loads the ghost of the room for the next round. It returns
+// false when the room has no ghost or its replay is gone.
+func (h *Hub) loadGhost() bool {
+	h.ghost = nil
+	g := h.settings.Ghost
+	if g == nil || h.store == nil {
+		return false
+	}
+
+	rep, err := h.store.GetReplay(g.RaceID)
+	if err != nil {
+		logger.Logger.Warn("[Ghost] Failed to load replay",
+			"roomId", h.roomId,
+			"raceId", g.RaceID,
+			"error", err,
+		)
+		return false
+	}
+	track, ok := rep.Track(g.UserID)
+	if !ok {
+		logger.Logger.Warn("[Ghost] Replay has no track for the ghost",
+			"roomId", h.roomId,
+			"raceId", g.RaceID,
+			"userId", g.UserID,
+		)
+		return false
+	}
+
+	h.ghost = &ghostRun{
+		id:       GhostIDPrefix + track.UserID,
+		name:     "Ghost of " + track.Name,
+		text:     rep.Text,
+		finished: track.Finished,
+		frames:   replay.Decode(track.Frames),
+	}
+	return true
+}
+
+// startGhost plays the ghost of the round back from startTime. The frames
+// are timed in their own goroutine and handed to the Run loop, which
+// broadcasts them.
+func (h *Hub) startGhost(startTime time.Time) {
+	h.stopGhost()
+	if h.ghost == nil || len(h.ghost.frames) == 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	h.ghostStop = stop
+	round, frames := h.round, h.ghost.frames
+	go func() {
+		for i, f := range frames {
+			timer := time.NewTimer(time.Until(startTime.Add(f.At)))
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			}
+			select {
+			case h.ghostFrames <- ghostFrame{round: round, frame: f, last: i == len(frames)-1}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	logger.Logger.Info("[Ghost] Ghost started",
+		"roomId", h.roomId,
+		"round", round,
+		"ghost", h.ghost.id,
+		"frames", len(frames),
+	)
+}
+
+// stopGhost stops the playback of the ghost
+func (h *Hub) stopGhost() {
+	if h.ghostStop != nil {
+		close(h.ghostStop)
+		h.ghostStop = nil
+	}
+}
+
+// handleGhostFrame broadcasts a frame of the ghost as its progress
+func (h *Hub) handleGhostFrame(f ghostFrame) {
+	if h.race == nil || h.ghost == nil || h.raceRecorded || f.round != h.round {
+		return
+	}
+
+	progress := race.Progress{
+		ID:   h.ghost.id,
+		Name: h.ghost.name,
+		Pos:  f.frame.Pos,
+		WPM:  f.frame.WPM,
+	}
+	if f.last && h.ghost.finished {
+		progress.Finished = true
+		progress.TimeMs = f.frame.At.Milliseconds()
+	}
+
+	h.broadcastRaceMessage(PlayerProgress, progress)
+	if progress.Finished {
+		h.broadcastRaceMessage(GameFinished, progress)
+	}
+}
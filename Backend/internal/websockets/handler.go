@@ -1,8 +1,12 @@
 package websockets
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ManogyaDahal/GoType/internal/auth"
 	"github.com/ManogyaDahal/GoType/internal/logger"
@@ -29,6 +33,7 @@ func getAllowedOrigins() []string {
 }
 
 var upgrader = &websocket.Upgrader{
+	Subprotocols: Subprotocols,
 	CheckOrigin: func(r *http.Request) bool {
 		origin := r.Header.Get("Origin")
 		for _, allowed := range getAllowedOrigins() {
@@ -58,8 +63,8 @@ func AuthenticatedWSHandler(m *HubManager) gin.HandlerFunc {
 		// Validate the HMAC-signed ws_token from the query string.
 		// This token was issued by WhoAmI and is valid for 2 hours.
 		wsToken := c.Query("token")
-		userName, valid := auth.ValidateWSToken(wsToken)
-		if !valid || userName == "" {
+		userID, userName, valid := auth.ValidateWSToken(wsToken)
+		if !valid || userID == "" {
 			c.JSON(http.StatusUnauthorized,
 				gin.H{"error": "Invalid or missing WebSocket token"})
 			return
@@ -69,23 +74,45 @@ func AuthenticatedWSHandler(m *HubManager) gin.HandlerFunc {
 		roomId := c.Query("room_id")
 		action := Action(c.Query("action"))
 
+		// Optional session resume: ?resume=<session token>&last_seq=<N>
+		resumeToken := c.Query("resume")
+		lastSeq, _ := strconv.ParseUint(c.Query("last_seq"), 10, 64)
+
 		if !IsValidAction(action) {
 			c.JSON(http.StatusBadRequest,
 				gin.H{"error": "Invalid action in the url"})
 			return
 		}
 
-		var currentHub *Hub
-		switch action {
-		case ActionJoin:
-			currentHub = m.GetExistringHub(roomId)
-			if currentHub == nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		// Optional protocol version: ?protocol=<N>, v1 when absent
+		protocol, err := NegotiateProtocol(c.Query("protocol"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest,
+				gin.H{"error": "Unsupported protocol version"})
+			return
+		}
+
+		currentHub := m.GetExistringHub(roomId)
+		if currentHub == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+
+		// Deleting a room needs no connection: close it and answer right away
+		if action == ActionDelete {
+			if currentHub.Info().HostID != userID {
+				c.JSON(http.StatusForbidden,
+					gin.H{"error": "Only the host can delete the room"})
 				return
 			}
+			m.DeleteHub(roomId, CloseReasonDeleted)
+			c.JSON(http.StatusOK,
+				gin.H{"room_id": roomId, "reason": CloseReasonDeleted})
+			return
 		}
 
-		// Upgrade HTTP connection to WebSocket
+		// Upgrade HTTP connection to WebSocket. The wire format is the
+		// subprotocol the client offered, JSON when it offered none.
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			logger.Logger.Error("[WS] WebSocket upgrade failed", "error", err)
@@ -97,22 +124,159 @@ func AuthenticatedWSHandler(m *HubManager) gin.HandlerFunc {
 			hub:        currentHub,
 			connection: conn,
 			send:       make(chan []byte, 256),
+			id:         userID,
 			name:       userName,
 			status:     StatusIdle,
+			joinedAt:   time.Now(),
+			spectator:  action == ActionSpectate,
+			protocol:   protocol,
+			encoding:   EncodingFor(conn.Subprotocol()),
+
+			resumeToken: resumeToken,
+			lastSeq:     lastSeq,
 		}
 
-		// Register the client with the hub
-		client.hub.register <- client
+		if client.spectator {
+			client.status = StatusSpectating
+		}
+
+		// Register the client with the hub, unless it was closed meanwhile
+		select {
+		case client.hub.register <- client:
+		case <-client.hub.Done():
+			conn.Close()
+			return
+		}
 
 		go client.ReadPump()
 		go client.WritePump()
 	}
 }
 
+// CreateNewRoom creates a room and makes the logged in user its host.
+// The optional JSON body holds the room settings, missing fields get the
+// defaults (see RoomSettings). {"ghost": {"user_id": "me"}} races against
+// the creator's best recorded race.
 func CreateNewRoom(m *HubManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		hub := m.CreateNewHub()
+		userID, _, ok := auth.SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
+
+		settings := DefaultRoomSettings()
+		if err := c.ShouldBindJSON(&settings); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room settings"})
+			return
+		}
+		if err := settings.Normalize(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := resolveGhost(m.store, &settings, userID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		hub := m.CreateNewHub(settings, userID)
 		c.JSON(http.StatusOK,
-			gin.H{"room_id": hub.roomId})
+			gin.H{"room_id": hub.roomId, "settings": settings})
+	}
+}
+
+// ListRooms returns the public rooms for the room browser.
+// Optional query filters: ?mode=words&state=lobby
+func ListRooms(m *HubManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := RoomFilter{
+			Mode:  RaceMode(c.Query("mode")),
+			State: RoomState(c.Query("state")),
+		}
+		if filter.Mode != "" && !IsValidMode(filter.Mode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode"})
+			return
+		}
+		if filter.State != "" && filter.State != RoomStateLobby && filter.State != RoomStateRacing {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"rooms": m.ListRooms(filter)})
+	}
+}
+
+// QuickMatch places the logged in user into an open public room of the
+// requested mode (JSON body {"mode": "words"}, defaults to words) or
+// creates one.
+func QuickMatch(m *HubManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _, ok := auth.SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
+
+		var req struct {
+			Mode RaceMode `json:"mode"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = ModeWords
+		}
+		if !IsValidMode(req.Mode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode"})
+			return
+		}
+
+		hub, created, err := m.QuickMatch(userID, req.Mode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"room_id": hub.roomId,
+			"created": created,
+		})
+	}
+}
+
+// RoomDetails returns the snapshot of a room (GET /api/rooms/:id)
+func RoomDetails(m *HubManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hub := m.GetExistringHub(c.Param("id"))
+		if hub == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		c.JSON(http.StatusOK, hub.Info())
+	}
+}
+
+// CloseRoom lets the host close a room (POST /api/rooms/:id/close). The
+// members get a room_closed message and are disconnected.
+func CloseRoom(m *HubManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _, ok := auth.SessionUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged In"})
+			return
+		}
+
+		roomId := c.Param("id")
+		hub := m.GetExistringHub(roomId)
+		if hub == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		if hub.Info().HostID != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the host can close the room"})
+			return
+		}
+
+		m.DeleteHub(roomId, CloseReasonHost)
+		c.JSON(http.StatusOK, gin.H{"room_id": roomId, "reason": CloseReasonHost})
 	}
 }
@@ -5,11 +5,16 @@ package websockets
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
 	"sync"
 	"time"
 
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/corpus"
 	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/race"
+	"github.com/ManogyaDahal/GoType/internal/rating"
+	"github.com/ManogyaDahal/GoType/internal/replay"
+	"github.com/ManogyaDahal/GoType/internal/storage"
 )
 
 // Grace period before deleting an empty hub.
@@ -19,8 +24,11 @@ const hubDeletionGracePeriod = 10 * time.Second
 
 //Manages all hubs
 type HubManager struct{
-	hubs map [string]*Hub //Stores the key for a hub
-	mu   sync.RWMutex	  //for concurrency safety
+	hubs  map [string]*Hub //Stores the key for a hub
+	mu    sync.RWMutex	  //for concurrency safety
+	store storage.Store    //persistent storage for rooms and races
+	ratings *rating.Updater //applies finished races to the skill ratings
+	telemetry *analytics.Recorder //aggregates keystroke telemetry into typing analytics
 }
 
 // Hub manages all central websocket connection with clients.
@@ -30,6 +38,9 @@ type Hub struct{
 	// All connected clients
 	clients 	 map[*Clients]bool
 
+	// Resumable sessions by token, including recently disconnected ones
+	sessions     map[string]*clientSession
+
 	//channel for broadcasting incoming messages
 	broadcast 	 chan Message
 
@@ -37,34 +48,102 @@ type Hub struct{
 	register     chan *Clients
 	unregistered chan *Clients
 
+	//channel for closing the room, done is closed once Run has stopped
+	closeRequests chan CloseReason
+	done          chan struct{}
+
 	//Hub manager
 	hubManager  *HubManager
 
+	//persistent storage, finished races are recorded here
+	store       storage.Store
+	ratings     *rating.Updater
+	telemetry   *analytics.Recorder
+
+	// Room configuration and ownership
+	settings     RoomSettings
+	hostID       string    // user ID of the host, the only one allowed to start / configure
+	state        RoomState // lobby or racing
+	createdAt    time.Time
+
+	// Snapshot for readers outside the Run loop (room browser, REST)
+	mu           sync.RWMutex
+	info         RoomInfo
+
 	// Timer for delayed hub deletion (grace period)
 	deleteTimer  *time.Timer
 
 	// Game countdown state
-	gameJoinedPlayers  map[string]bool // players who sent player_joined_game
+	gameJoinedPlayers  map[string]bool // IDs of players who sent player_joined_game
 	expectedPlayers    int             // snapshot of client count when game navigation started
 	gameCountdownActive bool           // true while countdown goroutine is running
+	startRequested     bool            // the host started the round, players are heading to the game page
+
+	// Race state
+	round        int        // number of rounds started in this room
+	textSeed     string     // seed the current text was generated from
+	raceText     string     // text of the current round, generated by the server
+	quote        *corpus.Quote // quote of the current round in quote mode
+	snippet      *corpus.Snippet // snippet of the current round in code mode
+	race         *race.Race // authoritative race, nil until the first game_go
+	raceRecorded bool       // true once the current race has been stored
+	raceTimer    *time.Timer // fires when the round's time limit expires
+	raceTimeouts chan int    // round numbers whose time limit expired, read by Run
+	sessionExpiries chan struct{} // a detached session may have expired, read by Run
+	keystrokes   map[string][]analytics.Keystroke // telemetry of the current race by player
+	watches      map[string]*playerWatch          // anti-cheat observations of the current race by player
+	replay       *replay.Recorder                 // progress stream of the current race
+
+	// Ghost of the room (see RoomSettings.Ghost), played back each round
+	ghost        *ghostRun
+	ghostStop    chan struct{}    // closed to stop the playback
+	ghostFrames  chan ghostFrame  // frames due for broadcast, read by Run
+
+	// Team of every player by user ID, see teams.go
+	teams        map[string]int
+
+	// Elimination match state
+	eliminated       map[string]bool // players knocked out of the current match
+	survivors        map[string]bool // players still in the current match, nil before its first round
+	eliminationRound int             // rounds played in the current match
+	nextRoundTimer   *time.Timer     // fires when the intermission is over
+	nextRounds       chan int        // rounds whose intermission is over, read by Run
 }
 
 //new hub manager
-func NewHubManager() *HubManager {
+func NewHubManager(store storage.Store) *HubManager {
 	return &HubManager{
 		hubs:make(map[string]*Hub) ,
+		store: store,
+		ratings: rating.NewUpdater(store),
+		telemetry: analytics.NewRecorder(store),
 	}
 }
 
-// Initializes a new hub
-func NewHub() *Hub {
+// Initializes a new hub owned by hostID
+func NewHub(settings RoomSettings, hostID string) *Hub {
 	return &Hub{
 		roomId:            GenerateRoomId(),
+		settings:          settings,
+		hostID:            hostID,
+		state:             RoomStateLobby,
+		createdAt:         time.Now(),
 		clients:           make(map[*Clients]bool),
+		sessions:          make(map[string]*clientSession),
 		broadcast:         make(chan Message, 100), //buffered channel to prevent deadlock
 		register:          make(chan *Clients, 5),
 		unregistered:      make(chan *Clients, 10),
+		closeRequests:     make(chan CloseReason, 1),
+		raceTimeouts:      make(chan int, 1),
+		sessionExpiries:   make(chan struct{}, 1),
+		ghostFrames:       make(chan ghostFrame, 16),
+		nextRounds:        make(chan int, 1),
+		eliminated:        make(map[string]bool),
+		teams:             make(map[string]int),
+		done:              make(chan struct{}),
 		gameJoinedPlayers: make(map[string]bool),
+		keystrokes:        make(map[string][]analytics.Keystroke),
+		watches:           make(map[string]*playerWatch),
 	}
 }
 
@@ -99,28 +178,64 @@ func (m *HubManager) GetExistringHub(roomID string) *Hub{
 }
 
 
-func (m *HubManager) CreateNewHub() *Hub {
+// CreateNewHub creates a room with the given settings, hostID becomes its host
+func (m *HubManager) CreateNewHub(settings RoomSettings, hostID string) *Hub {
 	//If hub is not found we create new hub by doing rw lock
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	newHub := NewHub()
+	newHub := NewHub(settings, hostID)
 	newHub.roomId = m.CheckIfRoomAlreadyExists(newHub.roomId)
 	// CHANGED: Set hubManager reference so hub can delete itself when empty
 	newHub.hubManager = m
+	newHub.store = m.store
+	newHub.ratings = m.ratings
+	newHub.telemetry = m.telemetry
+	newHub.publishInfo()
 	m.hubs[newHub.roomId] = newHub
 	go newHub.Run()
 
+	if err := m.store.SaveRoom(storage.Room{
+		ID:        newHub.roomId,
+		HostID:    hostID,
+		Mode:      string(settings.Mode),
+		Language:  settings.Language,
+		Private:   settings.Privacy == PrivacyPrivate,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		logger.Logger.Error("[HubManager] Failed to save room", "roomId", newHub.roomId, "error", err)
+	}
+
 	logger.Logger.Info("[HubManager] Created new hub", "roomId", newHub.roomId)
 	return newHub
 }
 
-//Deleting the empty hub
-func (m *HubManager) DeleteHub(roomId string){
+// DeleteHub removes the hub and closes it: members are notified with the
+// reason and disconnected, and the hub's Run goroutine stops.
+func (m *HubManager) DeleteHub(roomId string, reason CloseReason) bool {
+	hub := m.GetExistringHub(roomId)
+	if hub == nil {
+		return false
+	}
+	m.removeHub(roomId, reason)
+	hub.Close(reason)
+	return true
+}
+
+// removeHub forgets the hub and records when and why it was closed
+func (m *HubManager) removeHub(roomId string, reason CloseReason){
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	delete(m.hubs, roomId)
-	logger.Logger.Info("[HubManager] Deleted hub", "roomId", roomId)
+	m.mu.Unlock()
+	logger.Logger.Info("[HubManager] Deleted hub", "roomId", roomId, "reason", reason)
+
+	if room, err := m.store.GetRoom(roomId); err == nil {
+		room.ClosedAt = time.Now()
+		room.CloseReason = string(reason)
+		if err := m.store.SaveRoom(room); err != nil {
+			logger.Logger.Error("[HubManager] Failed to save room", "roomId", roomId, "error", err)
+		}
+	}
 }
 
 //Run is the main event loop
@@ -129,7 +244,18 @@ func (h *Hub)Run(){
 		//it might result in deadlock (empty select)
 		select {
 		case client := <-h.register:
+			if !h.canJoin(client) {
+				reason := "Room is full"
+				if client.spectator {
+					reason = "No spectator seats available"
+				}
+				h.rejectClient(client, reason)
+				continue
+			}
+			h.attachSession(client)
+			h.applyElimination(client)
 			h.clients[client] = true
+			h.syncTeams()
 			// Cancel any pending deletion timer — a player reconnected
 			if h.deleteTimer != nil {
 				h.deleteTimer.Stop()
@@ -139,6 +265,7 @@ func (h *Hub)Run(){
 					"client", client.name,
 				)
 			}
+			h.sendTo(client, h.settingsMessage())
 			h.BroadcastPlayerList()
 			h.EventReport(client, "[hub]", Info, "NewClient registered", nil)
 			// SendSystemMessages(UserJoinedSysMessage, client, h)
@@ -146,6 +273,8 @@ func (h *Hub)Run(){
 		case client := <-h.unregistered:
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
+				h.detachSession(client)
+				h.pruneSessions(time.Now())
 				close(client.send) //edit
 				h.BroadcastPlayerList()
 				h.EventReport(client, "[hub]", Warning, "Client Unregistered", nil)
@@ -175,10 +304,27 @@ func (h *Hub)Run(){
 			if client == nil && len(h.clients) == 0 && h.hubManager != nil {
 				logger.Logger.Info("[HubManager] Deleting hub after grace period (still empty)",
 					"roomId", h.roomId)
-				h.hubManager.DeleteHub(h.roomId)
+				h.hubManager.removeHub(h.roomId, CloseReasonEmpty)
+				h.shutdown(CloseReasonEmpty)
 				return
 			}
 
+		case round := <-h.raceTimeouts:
+			h.handleRaceTimeout(round)
+
+		case <-h.sessionExpiries:
+			h.pruneSessions(time.Now())
+
+		case frame := <-h.ghostFrames:
+			h.handleGhostFrame(frame)
+
+		case round := <-h.nextRounds:
+			h.handleNextRound(round)
+
+		case reason := <-h.closeRequests:
+			h.shutdown(reason)
+			return
+
 		case msg := <-h.broadcast:
 			logger.Logger.Debug("[Hub] Message received",
 				"room_id", h.roomId,
@@ -200,9 +346,9 @@ func (h *Hub)Run(){
 
 //For error reports
 func (h *Hub) EventReport(c *Clients, src string, sev Severity, msg string, err error) {
-    clientName := "unknown"
+    clientName, clientID := "unknown", ""
     if c != nil && c.name != "" {
-        clientName = c.name
+        clientName, clientID = c.name, c.id
     }
 
     switch sev {
@@ -210,6 +356,7 @@ func (h *Hub) EventReport(c *Clients, src string, sev Severity, msg string, err
         logger.Logger.Info(msg,
             "room_id", h.roomId,
             "client", clientName,
+            "client_id", clientID,
             "source", src,
             "error", err,
         )
@@ -217,13 +364,24 @@ func (h *Hub) EventReport(c *Clients, src string, sev Severity, msg string, err
         logger.Logger.Warn(msg,
             "room_id", h.roomId,
             "client", clientName,
+            "client_id", clientID,
+            "source", src,
+            "error", err,
+        )
+    case Cheat:
+        logger.Logger.Warn(msg,
+            "room_id", h.roomId,
+            "client", clientName,
+            "client_id", clientID,
             "source", src,
+            "severity", sev,
             "error", err,
         )
     case Error, Fatal:
         logger.Logger.Error(msg,
             "room_id", h.roomId,
             "client", clientName,
+            "client_id", clientID,
             "source", src,
             "error", err,
         )
@@ -243,25 +401,41 @@ func (h *Hub) ResetGameState() {
 
 // PlayerJoinedGame marks a player as having arrived on the game page.
 // When all expected players have joined, it kicks off the countdown.
-func (h *Hub) PlayerJoinedGame(playerName string) {
-	h.gameJoinedPlayers[playerName] = true
+// Arrivals before the host started the round are ignored, only the host
+// starts a round.
+func (h *Hub) PlayerJoinedGame(playerID string) {
+	// Spectators follow the players to the game page but are never waited for
+	if h.isSpectator(playerID) {
+		return
+	}
+	if !h.startRequested {
+		logger.Logger.Warn("[Hub] Player joined game before the host started it",
+			"player", playerID,
+			"roomId", h.roomId,
+		)
+		return
+	}
+	h.gameJoinedPlayers[playerID] = true
 
-	// If we don't have an expected count yet, snapshot the current client count.
+	// If we don't have an expected count yet, snapshot the current player count.
 	// This handles the first player_joined_game arriving.
 	if h.expectedPlayers == 0 {
-		h.expectedPlayers = len(h.clients)
+		h.expectedPlayers = h.playerCount()
 	}
 
 	logger.Logger.Info("[Hub] Player joined game",
-		"player", playerName,
+		"player", playerID,
 		"joined", len(h.gameJoinedPlayers),
 		"expected", h.expectedPlayers,
 		"roomId", h.roomId,
 	)
 
-	// If all expected players are in, send game_go (only once)
+	// If all expected players are in, send the round text and game_go (only once)
 	if len(h.gameJoinedPlayers) >= h.expectedPlayers && !h.gameCountdownActive {
 		h.gameCountdownActive = true
+		h.startRequested = false
+		h.setState(RoomStateRacing)
+		h.startRound()
 		h.sendGameGo()
 	}
 }
@@ -272,16 +446,26 @@ func (h *Hub) PlayerJoinedGame(playerName string) {
 // that sent 4 separate messages (countdown 3, 2, 1, then go) which was
 // prone to dropped messages causing clients to get stuck at countdown "1".
 func (h *Hub) sendGameGo() {
-	// Start time is 3 seconds from now — gives clients time to show 3-2-1
-	const countdownDuration = 3 * time.Second
-	startTime := time.Now().Add(countdownDuration).UnixMilli()
-
-	content, _ := json.Marshal(map[string]int64{"start_time": startTime})
+	// Start time is a few seconds from now (room setting, 3 by default) —
+	// gives clients time to show 3-2-1
+	countdownDuration := time.Duration(h.settings.Countdown) * time.Second
+	start := time.Now().Add(countdownDuration)
+	startTime := start.UnixMilli()
+
+	// The race engine starts accepting input at the shared start time
+	h.startRace(start)
+
+	// Timed rounds also carry their deadline, so clients can count down
+	payload := GoPayload{StartTime: startTime}
+	if h.race != nil && h.race.Timed() {
+		payload.EndTime = h.race.Deadline().UnixMilli()
+	}
+	content, _ := encodeContent(payload)
 	msg := Message{
 		Type:      GameGo,
 		RoomId:    h.roomId,
 		Sender:    "server",
-		Content:   json.RawMessage(content),
+		Content:   content,
 		TimeStamp: time.Now(),
 	}
 
@@ -298,39 +482,38 @@ func (h *Hub) sendGameGo() {
 }
 
 func (h *Hub) BroadcastPlayerList() {
-    players := make([]map[string]interface{}, 0, len(h.clients))
+    // Every player list change is also a room browser change
+    h.publishInfo()
+
+    players := make(PlayerListPayload, 0, len(h.clients))
     for client := range h.clients {
+        if client.spectator {
+            continue
+        }
         status := client.status
         if status == "" {
             status = StatusIdle
         }
-        players = append(players, map[string]interface{}{
-            "name":   client.name,
-            "ready":  status == StatusReady, // backward compat for any code still checking .ready
-            "status": status,
+        players = append(players, PlayerInfo{
+            ID:     client.id,
+            Name:   client.name,
+            Ready:  status == StatusReady,
+            Status: status,
+            Host:   client.id == h.hostID,
+            Team:   h.teamOf(client.id),
         })
     }
 
-    // Step 1: Marshal players to []byte
-    data, err := json.Marshal(players)
+    // v1 clients get it wrapped in a JSON string, see forProtocol
+    content, err := encodeContent(players)
     if err != nil {
 				logger.Logger.Warn("[Hub] Failed to marshal player list", "error", err)
         return
     }
 
-    // Step 2: Convert []byte to string
-    contentStr := string(data)  // ← CORRECT: string(data)
-
-    // Step 3: Marshal string to JSON (adds quotes + escapes)
-    contentJSON, err := json.Marshal(contentStr)
-    if err != nil {
-				logger.Logger.Warn("[Hub] Failed to marshal content string", "error", err)
-        return
-    }
-
     msg := Message{
-        Type:      "player_list",
-        Content:   json.RawMessage(contentJSON),
+        Type:      PlayerListMessage,
+        Content:   content,
         RoomId:    h.roomId,
         TimeStamp: time.Now(),
     }
@@ -340,4 +523,6 @@ func (h *Hub) BroadcastPlayerList() {
     default:
 				logger.Logger.Warn("[Hub] Broadcast channel full, dropping player_list", "roomId", h.roomId)
     }
+
+    h.broadcastSpectatorList()
 }
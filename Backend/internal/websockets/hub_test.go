@@ -0,0 +1,243 @@
+package websockets
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/race"
+	"github.com/ManogyaDahal/GoType/internal/replay"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// newTestHub returns a hub with settings where the players are connected,
+// the first one hosting. Nothing runs the hub, the tests call its handlers
+// the way the Run loop would.
+func newTestHub(t *testing.T, settings RoomSettings, players ...string) *Hub {
+	t.Helper()
+	logger.InitLogger("")
+	h := NewHub(settings, players[0])
+	for _, id := range players {
+		connect(h, newTestClient(id))
+	}
+	return h
+}
+
+// connect adds a client to the hub the way registering does
+func connect(h *Hub, c *Clients) {
+	h.attachSession(c)
+	h.clients[c] = true
+}
+
+// newTestClient returns a connection of the user buffering what it's sent
+func newTestClient(id string) *Clients {
+	return &Clients{id: id, name: id, send: make(chan []byte, 256), joinedAt: time.Now(), protocol: LatestProtocol, encoding: EncodingFor("")}
+}
+
+// startTestRace starts a race over text at start with the connected players
+func startTestRace(t *testing.T, h *Hub, text string, start time.Time) {
+	t.Helper()
+	var err error
+	if h.race, err = race.New(text, start); err != nil {
+		t.Fatal(err)
+	}
+	for client := range h.clients {
+		if !client.spectator {
+			h.race.Join(client.id, client.name)
+		}
+	}
+	h.replay = replay.NewRecorder(text, "words", "en", start)
+}
+
+// submit sends a player_progress of the player to the hub
+func submit(h *Hub, userID string, in race.Input) {
+	content, _ := encodeContent(in)
+	h.handleProgress(Message{Type: PlayerProgress, Sender: userID, Content: content})
+}
+
+// received returns the messages of msgType the client was sent so far, all
+// of them for an empty msgType
+func received(t *testing.T, c *Clients, msgType string) []Message {
+	t.Helper()
+	var out []Message
+	for {
+		select {
+		case data, ok := <-c.send:
+			if !ok {
+				return out
+			}
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("undecodable message to %s: %v", c.id, err)
+			}
+			if msgType == "" || msg.Type == msgType {
+				out = append(out, msg)
+			}
+		default:
+			return out
+		}
+	}
+}
+
+// Only the host starts a round: players arriving on the game page on
+// their own are ignored
+func TestRoundStartsOnlyFromHost(t *testing.T) {
+	h := newTestHub(t, DefaultRoomSettings(), "host", "guest")
+
+	h.PlayerJoinedGame("guest")
+	h.handleStartRequest(Message{Type: GameStart, Sender: "guest"})
+	h.PlayerJoinedGame("host")
+	if len(h.gameJoinedPlayers) != 0 || h.state != RoomStateLobby {
+		t.Fatalf("round started without the host: %d joined, state %s", len(h.gameJoinedPlayers), h.state)
+	}
+
+	h.handleStartRequest(Message{Type: GameStart, Sender: "host"})
+	h.PlayerJoinedGame("guest")
+	if h.state != RoomStateLobby {
+		t.Fatalf("round started before every player joined")
+	}
+	h.PlayerJoinedGame("host")
+	if h.state != RoomStateRacing || h.race == nil {
+		t.Fatalf("round not started once the host started it, state %s", h.state)
+	}
+	if h.startRequested {
+		t.Error("start request still pending after the round started")
+	}
+}
+
+// The replay follows what the engine verified, never the cursor hint of
+// the client
+func TestReplayRecordsVerifiedPosition(t *testing.T) {
+	h := newTestHub(t, DefaultRoomSettings(), "host")
+	startTestRace(t, h, "the quick fox", time.Now().Add(-time.Second))
+
+	hint := 3
+	submit(h, "host", race.Input{Keys: "th", Pos: &hint})
+
+	rep := h.replay.Replay("race", nil)
+	if len(rep.Tracks) != 1 {
+		t.Fatalf("%d tracks, want 1", len(rep.Tracks))
+	}
+	frames := replay.Decode(rep.Tracks[0].Frames)
+	if len(frames) != 1 || frames[0].Pos != 2 {
+		t.Errorf("frames %+v, want one at the verified position 2", frames)
+	}
+}
+
+// A player who disconnected mid-race and can't resume any more no longer
+// holds the race open
+func TestExpiredSessionEndsRace(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	h := newTestHub(t, DefaultRoomSettings(), "host")
+	h.sessions["gone"] = &clientSession{token: "gone", userID: "guest", detachedAt: start}
+	startTestRace(t, h, "go", start)
+	h.race.Join("guest", "guest")
+
+	submit(h, "host", race.Input{Keys: "go"})
+	if h.race == nil {
+		t.Fatal("race over while a player can still resume")
+	}
+
+	h.pruneSessions(start.Add(sessionResumeWindow + time.Second))
+	if h.race != nil || !h.raceRecorded {
+		t.Error("race still waiting for a player whose session expired")
+	}
+}
+
+// A host who lost the socket keeps the role while they can resume, the
+// role moves on once their session expired
+func TestHostKeptWhileResumable(t *testing.T) {
+	logger.InitLogger("")
+	h := NewHub(DefaultRoomSettings(), "host")
+	newClient := func(id, resumeToken string) *Clients {
+		c := newTestClient(id)
+		c.resumeToken = resumeToken
+		connect(h, c)
+		return c
+	}
+	host := newClient("host", "")
+	newClient("guest", "")
+
+	drop := func(c *Clients) {
+		delete(h.clients, c)
+		h.detachSession(c)
+		h.pruneSessions(time.Now())
+	}
+	drop(host)
+	if h.hostID != "host" {
+		t.Fatalf("host role moved to %q on a dropped socket", h.hostID)
+	}
+
+	back := newClient("host", host.session.token)
+	if back.session != host.session || h.hostID != "host" {
+		t.Fatalf("host %q after resuming, want host", h.hostID)
+	}
+
+	drop(back)
+	h.pruneSessions(time.Now().Add(sessionResumeWindow + time.Second))
+	if h.hostID != "guest" {
+		t.Errorf("host %q once the session expired, want guest", h.hostID)
+	}
+}
+
+// A race against a ghost is recorded, but unranked
+func TestGhostRaceUnranked(t *testing.T) {
+	h := newTestHub(t, DefaultRoomSettings(), "host")
+	h.store = storage.NewMemoryStore()
+	h.ghost = &ghostRun{id: GhostIDPrefix + "old", name: "Ghost of Old", text: "go"}
+	startTestRace(t, h, "go", time.Now().Add(-time.Second))
+
+	submit(h, "host", race.Input{Keys: "go"})
+
+	// Saving happens off the Run loop
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if races, _ := h.store.ListRaces(storage.RaceFilter{}); len(races) == 1 {
+			if !races[0].Unranked {
+				t.Error("ghost race recorded as ranked")
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("ghost race not recorded")
+}
+
+// Later rounds of an elimination match are raced by its survivors only,
+// a player who joined during the intermission waits for the next match
+func TestEliminationRoundsKeepToSurvivors(t *testing.T) {
+	settings := DefaultRoomSettings()
+	settings.Mode = ModeElimination
+	h := newTestHub(t, settings, "a", "b", "c")
+	h.state = RoomStateRacing
+	h.startRound()
+	h.startRace(time.Now().Add(-time.Second))
+
+	// c does not finish and is out
+	text := h.race.Text()
+	for _, id := range []string{"a", "b"} {
+		submit(h, id, race.Input{Keys: text})
+	}
+	h.finishRace(RaceEndTimeout)
+	h.stopNextRoundTimer()
+	if !h.eliminated["c"] || h.survivorCount() != 2 {
+		t.Fatalf("eliminated %v, %d survivors, want c out and 2 left", h.eliminated, h.survivorCount())
+	}
+
+	connect(h, newTestClient("d"))
+	h.handleNextRound(h.round)
+	if h.race == nil {
+		t.Fatal("next round not started")
+	}
+	for id, want := range map[string]bool{"a": true, "b": true, "c": false, "d": false} {
+		if got := h.race.Player(id) != nil; got != want {
+			t.Errorf("%s racing the second round: %v, want %v", id, got, want)
+		}
+	}
+
+	h.endElimination(nil)
+	if !h.inMatch(h.clientByID("d")) || !h.inMatch(h.clientByID("c")) {
+		t.Error("players left out of the next match")
+	}
+}
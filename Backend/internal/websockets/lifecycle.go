@@ -0,0 +1,109 @@
+// This file implements the end of a room's life. A room is closed either
+// by its host (close_room message, action=delete, REST) or by the server
+// when it stays empty past the grace period.
+// Closing notifies every member with the reason, disconnects them cleanly
+// and stops the hub's Run goroutine.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// CloseReason tells why a room was closed
+type CloseReason string
+
+const (
+	CloseReasonHost    CloseReason = "closed_by_host"  // the host closed the room
+	CloseReasonDeleted CloseReason = "deleted_by_host" // the host deleted the room (action=delete)
+	CloseReasonEmpty   CloseReason = "empty"           // everybody left and the grace period passed
+)
+
+// ClosedPayload is the content of a room_closed message
+type ClosedPayload struct {
+	Reason  CloseReason `json:"reason"`
+	Message string      `json:"message"`
+}
+
+// Close asks the Run loop to close the room. It never blocks and is a
+// no-op once the room is closed.
+func (h *Hub) Close(reason CloseReason) {
+	select {
+	case h.closeRequests <- reason:
+	case <-h.done:
+	default:
+		// a close request is already pending
+	}
+}
+
+// Done is closed once the Run loop has stopped
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// shutdown notifies the members, disconnects them and marks the hub as
+// done. Must be called from the Run loop, which returns right after.
+func (h *Hub) shutdown(reason CloseReason) {
+	if h.deleteTimer != nil {
+		h.deleteTimer.Stop()
+		h.deleteTimer = nil
+	}
+	h.stopRaceTimer()
+	h.stopGhost()
+	h.stopNextRoundTimer()
+
+	content, err := encodeContent(ClosedPayload{Reason: reason, Message: closeReasonText(reason)})
+	if err != nil {
+		logger.Logger.Error("[Hub] Failed to encode room_closed", "error", err)
+	}
+	h.broadcastAll(Message{
+		Type:      RoomClosed,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+
+	// Closing send makes every WritePump send a close frame and hang up
+	for client := range h.clients {
+		delete(h.clients, client)
+		close(client.send)
+	}
+	h.sessions = make(map[string]*clientSession)
+	h.publishInfo()
+
+	logger.Logger.Info("[Hub] Room closed",
+		"roomId", h.roomId,
+		"reason", reason,
+	)
+	// Closed last: once Done fires the hub is no longer touched
+	close(h.done)
+}
+
+func closeReasonText(reason CloseReason) string {
+	switch reason {
+	case CloseReasonHost:
+		return "The host closed the room"
+	case CloseReasonDeleted:
+		return "The host deleted the room"
+	case CloseReasonEmpty:
+		return "The room was closed because it was empty"
+	default:
+		return "The room was closed"
+	}
+}
+
+// handleCloseRequest lets the host close the room from the websocket. The
+// room is shut down on the next turn of the Run loop.
+func (h *Hub) handleCloseRequest(message Message) {
+	if !h.isHost(message.Sender) {
+		h.sendError(message.Sender, "Only the host can close the room")
+		return
+	}
+	if h.hubManager != nil {
+		h.hubManager.removeHub(h.roomId, CloseReasonHost)
+	}
+	h.Close(CloseReasonHost)
+}
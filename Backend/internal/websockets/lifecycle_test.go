@@ -0,0 +1,103 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// newRunningRoom creates a room through the manager and connects the
+// players to its running hub
+func newRunningRoom(t *testing.T, m *HubManager, players ...string) (*Hub, []*Clients) {
+	t.Helper()
+	logger.InitLogger("")
+	h := m.CreateNewHub(DefaultRoomSettings(), players[0])
+	clients := make([]*Clients, len(players))
+	for i, id := range players {
+		clients[i] = newTestClient(id)
+		h.register <- clients[i]
+	}
+	deadline := time.Now().Add(time.Second)
+	for h.Info().PlayerCount < len(players) {
+		if time.Now().After(deadline) {
+			t.Fatal("players not registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return h, clients
+}
+
+// waitClosed waits for the Run loop of the hub to stop
+func waitClosed(t *testing.T, h *Hub) {
+	t.Helper()
+	select {
+	case <-h.Done():
+	case <-time.After(time.Second):
+		t.Fatal("room not closed")
+	}
+}
+
+// closedWith checks the client was told the room closed for reason and
+// then disconnected
+func closedWith(t *testing.T, c *Clients, reason CloseReason) []Message {
+	t.Helper()
+	msgs := received(t, c, "")
+	if _, open := <-c.send; open {
+		t.Errorf("%s still connected", c.id)
+	}
+	var closed ClosedPayload
+	if len(msgs) == 0 || msgs[len(msgs)-1].Type != RoomClosed {
+		t.Errorf("%s: room_closed not the last message", c.id)
+	} else if err := msgs[len(msgs)-1].decodeContent(&closed); err != nil || closed.Reason != reason || closed.Message == "" {
+		t.Errorf("%s: room_closed %+v, want %s", c.id, closed, reason)
+	}
+	return msgs
+}
+
+// Only the host closes the room from the websocket, everybody is notified
+// and disconnected and the room is gone
+func TestCloseRoom(t *testing.T) {
+	store := storage.NewMemoryStore()
+	m := NewHubManager(store)
+	h, clients := newRunningRoom(t, m, "host", "guest")
+
+	h.broadcast <- Message{Type: CloseRoomMessage, Sender: "guest"}
+	h.broadcast <- Message{Type: CloseRoomMessage, Sender: "host"}
+	waitClosed(t, h)
+
+	closedWith(t, clients[0], CloseReasonHost)
+	refused := false
+	for _, msg := range closedWith(t, clients[1], CloseReasonHost) {
+		refused = refused || msg.Type == ErrorMessage
+	}
+	if !refused {
+		t.Error("guest closing the room not refused")
+	}
+
+	if m.GetExistringHub(h.roomId) != nil {
+		t.Error("closed room still listed")
+	}
+	if room, err := store.GetRoom(h.roomId); err != nil || room.CloseReason != string(CloseReasonHost) || room.ClosedAt.IsZero() {
+		t.Errorf("stored room %+v (%v), want closed by the host", room, err)
+	}
+	h.Close(CloseReasonHost) // no-op once closed, never blocks
+}
+
+// DeleteHub stops the room's Run loop and disconnects its members
+func TestDeleteHub(t *testing.T) {
+	m := NewHubManager(storage.NewMemoryStore())
+	h, clients := newRunningRoom(t, m, "host", "guest")
+
+	if !m.DeleteHub(h.roomId, CloseReasonDeleted) {
+		t.Fatal("room not found")
+	}
+	waitClosed(t, h)
+	for _, c := range clients {
+		closedWith(t, c, CloseReasonDeleted)
+	}
+	if m.DeleteHub(h.roomId, CloseReasonDeleted) {
+		t.Error("room deleted twice")
+	}
+}
@@ -0,0 +1,76 @@
+// This file implements the public room browser and quick-match.
+
+package websockets
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrNoQuickMatch is returned by QuickMatch for a mode whose text comes from
+// the host, such rooms can only be created by hand
+var ErrNoQuickMatch = errors.New("quick match is not available for this mode")
+
+// RoomFilter narrows down ListRooms. Zero values mean "no filter".
+type RoomFilter struct {
+	Mode  RaceMode
+	State RoomState
+}
+
+// ListRooms returns a snapshot of every public room matching the filter,
+// rooms with the most players first
+func (m *HubManager) ListRooms(f RoomFilter) []RoomInfo {
+	m.mu.RLock()
+	hubs := make([]*Hub, 0, len(m.hubs))
+	for _, hub := range m.hubs {
+		hubs = append(hubs, hub)
+	}
+	m.mu.RUnlock()
+
+	rooms := make([]RoomInfo, 0, len(hubs))
+	for _, hub := range hubs {
+		info := hub.Info()
+		if info.Settings.Privacy != PrivacyPublic {
+			continue
+		}
+		if f.Mode != "" && info.Settings.Mode != f.Mode {
+			continue
+		}
+		if f.State != "" && info.State != f.State {
+			continue
+		}
+		rooms = append(rooms, info)
+	}
+
+	sort.SliceStable(rooms, func(i, j int) bool {
+		if rooms[i].PlayerCount != rooms[j].PlayerCount {
+			return rooms[i].PlayerCount > rooms[j].PlayerCount
+		}
+		return rooms[i].CreatedAt.Before(rooms[j].CreatedAt)
+	})
+	return rooms
+}
+
+// QuickMatch returns an open public room in the lobby with the requested
+// mode, preferring the fullest one so races fill up quickly. When no room
+// has a free seat a new public room is created with userID as its host.
+func (m *HubManager) QuickMatch(userID string, mode RaceMode) (*Hub, bool, error) {
+	if mode == ModeCustom {
+		return nil, false, ErrNoQuickMatch
+	}
+	settings := DefaultRoomSettings()
+	settings.Mode = mode
+	if err := settings.Normalize(); err != nil {
+		return nil, false, err
+	}
+
+	for _, info := range m.ListRooms(RoomFilter{Mode: mode, State: RoomStateLobby}) {
+		if info.PlayerCount >= info.Settings.MaxPlayers {
+			continue
+		}
+		if hub := m.GetExistringHub(info.ID); hub != nil {
+			return hub, false, nil
+		}
+	}
+	return m.CreateNewHub(settings, userID), true, nil
+}
@@ -0,0 +1,97 @@
+package websockets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// addTestRoom adds a room with settings and players to the manager. Its
+// hub isn't run, the room browser only reads the published snapshot.
+func addTestRoom(t *testing.T, m *HubManager, settings RoomSettings, state RoomState, players ...string) *Hub {
+	t.Helper()
+	h := newTestHub(t, settings, players...)
+	h.state = state
+	h.publishInfo()
+	m.hubs[h.roomId] = h
+	return h
+}
+
+func TestListRooms(t *testing.T) {
+	m := NewHubManager(storage.NewMemoryStore())
+	words := DefaultRoomSettings()
+	quotes := DefaultRoomSettings()
+	quotes.Mode = ModeQuote
+	private := DefaultRoomSettings()
+	private.Privacy = PrivacyPrivate
+
+	small := addTestRoom(t, m, words, RoomStateLobby, "a")
+	big := addTestRoom(t, m, words, RoomStateRacing, "b", "c", "d")
+	quote := addTestRoom(t, m, quotes, RoomStateLobby, "e", "f")
+	addTestRoom(t, m, private, RoomStateLobby, "g", "h", "i", "j")
+
+	tests := []struct {
+		name   string
+		filter RoomFilter
+		want   []*Hub
+	}{
+		{"public rooms, fullest first", RoomFilter{}, []*Hub{big, quote, small}},
+		{"by mode", RoomFilter{Mode: ModeWords}, []*Hub{big, small}},
+		{"by state", RoomFilter{State: RoomStateLobby}, []*Hub{quote, small}},
+		{"by mode and state", RoomFilter{Mode: ModeQuote, State: RoomStateRacing}, nil},
+	}
+	for _, tt := range tests {
+		rooms := m.ListRooms(tt.filter)
+		if len(rooms) != len(tt.want) {
+			t.Errorf("%s: %d rooms, want %d", tt.name, len(rooms), len(tt.want))
+			continue
+		}
+		for i, h := range tt.want {
+			if rooms[i].ID != h.roomId {
+				t.Errorf("%s: room %d is %s, want %s", tt.name, i, rooms[i].ID, h.roomId)
+			}
+		}
+	}
+
+	if rooms := m.ListRooms(RoomFilter{Mode: ModeWords}); rooms[0].PlayerCount != 3 || rooms[0].HostID != "b" {
+		t.Errorf("room info %+v, want 3 players hosted by b", rooms[0])
+	}
+}
+
+func TestQuickMatch(t *testing.T) {
+	m := NewHubManager(storage.NewMemoryStore())
+	words := DefaultRoomSettings()
+	full := DefaultRoomSettings()
+	full.MaxPlayers = MinMaxPlayers
+
+	addTestRoom(t, m, words, RoomStateRacing, "a", "b", "c")
+	addTestRoom(t, m, full, RoomStateLobby, "d", "e")
+	open := addTestRoom(t, m, words, RoomStateLobby, "f")
+	fuller := addTestRoom(t, m, words, RoomStateLobby, "g", "h")
+
+	hub, created, err := m.QuickMatch("me", ModeWords)
+	if err != nil || created || hub != fuller {
+		t.Fatalf("QuickMatch = %v, %v, want the fullest open room in the lobby", created, err)
+	}
+
+	delete(m.hubs, fuller.roomId)
+	if hub, _, _ := m.QuickMatch("me", ModeWords); hub != open {
+		t.Fatal("QuickMatch skipped the open room")
+	}
+
+	hub, created, err = m.QuickMatch("me", ModeQuote)
+	if err != nil || !created {
+		t.Fatalf("QuickMatch(quote) = %v, %v, want a new room", created, err)
+	}
+	info := hub.Info()
+	if info.HostID != "me" || info.Settings.Mode != ModeQuote || info.Settings.Privacy != PrivacyPublic {
+		t.Errorf("new room %+v, want a public quote room hosted by me", info)
+	}
+	m.DeleteHub(hub.roomId, CloseReasonEmpty)
+	<-hub.Done()
+
+	if _, _, err := m.QuickMatch("me", ModeCustom); !errors.Is(err, ErrNoQuickMatch) {
+		t.Errorf("QuickMatch(custom) error %v, want ErrNoQuickMatch", err)
+	}
+}
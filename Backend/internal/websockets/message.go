@@ -11,12 +11,16 @@ import (
 
 // Defines the attributes of messages to be sent
 type Message struct {
-	Type      string          `json:"type"`      // Type of message private, broadcast
-	RoomId    string          `json:"room_id"`   // roomId: id of hub
-	Sender    string          `json:"sender"`    // Client's name
-	Reciever  string          `json:"reciever"`  // Reciever's name {if private message}
-	Content   json.RawMessage `json:"content"`   // content which the message holds
-	TimeStamp time.Time       `json:"timestamp"` // Time of message arrival
+	Type       string          `json:"type"`        // Type of message private, broadcast
+	RoomId     string          `json:"room_id"`     // roomId: id of hub
+	Sender     string          `json:"sender"`      // Client's user ID
+	SenderName string          `json:"sender_name"` // Client's display name
+	Reciever   string          `json:"reciever"`    // Reciever's user ID {if private message}
+	Content    json.RawMessage `json:"content"`     // content which the message holds
+	TimeStamp  time.Time       `json:"timestamp"`   // Time of message arrival
+	Seq        uint64          `json:"seq,omitempty"` // per-client sequence number, set when sent
+
+	protocol int // protocol version of the sending client, 0 for messages of the hub itself
 }
 
 // Type of the messages
@@ -28,14 +32,32 @@ const (
 	PlayerReadyToggle string = "ready_toggle" // informs about the ready state
 
 	// Game message types
-	PlayerProgress    string = "player_progress"     // relay cursor position + WPM to other players
-	GameFinished      string = "game_finished"       // relay when a player finishes the game
-	GameStart         string = "game_start"          // broadcast game text to all players when game begins
+	PlayerProgress    string = "player_progress"     // client → server: typed word / keys, server → clients: verified position + WPM
+	GameFinished      string = "game_finished"       // server → clients: a player finished (place, WPM, accuracy)
+	GameStart         string = "game_start"          // host → server: start the round, server → clients: text of the round
 	RequestPlayerList string = "request_player_list" // client requests a fresh player list
 	ResetReady        string = "reset_ready"         // client asks server to set their ready state to false
 	PlayerJoinedGame  string = "player_joined_game"  // client signals it has arrived on the game page
-	GameCountdown     string = "game_countdown"      // server → clients: countdown tick (3, 2, 1)
+	GameCountdown     string = "game_countdown"      // server → clients: the host started the round, seconds before the game page
 	GameGo            string = "game_go"             // server → clients: game starts now (includes start_time)
+	RaceResultsMessage string = "race_results"       // server → clients: final standings once the race is over
+	KeystrokesMessage string = "keystrokes"          // client → server: batch of key presses (optional telemetry)
+	TextSourceMessage string = "text_source"         // server → clients: attribution of the round's quote or snippet
+	PlayerEliminatedMessage  string = "player_eliminated"  // server → clients: a player is out of the elimination match
+	EliminationWinnerMessage string = "elimination_winner" // server → clients: the last player standing won the match
+
+	// Connection message types
+	SessionMessage string = "session" // server → client: resumable session token + last seq
+	ErrorMessage   string = "error"   // server → client: a request was refused (content: reason)
+
+	// Room message types
+	RoomSettingsMessage string = "room_settings" // host → server: change settings, server → clients: current settings
+	HostChanged         string = "host_changed"  // server → clients: the host role moved to another player
+	SpectatorListMessage string = "spectator_list" // server → clients: spectators watching the room
+	CloseRoomMessage    string = "close_room"    // host → server: close the room for everyone
+	TeamAssignMessage   string = "team_assign"   // host → server: move a player to a team
+	TeamBalanceMessage  string = "team_balance"  // host → server: balance the teams by rating
+	RoomClosed          string = "room_closed"   // server → clients: the room was closed (content: reason + message)
 )
 
 // type of system messages
@@ -45,13 +67,15 @@ const (
 	NewHubCreated        string = "newHubCreated"
 )
 
-func encodeMessage(msg Message) []byte {
-	data, err := json.Marshal(msg)
+// encodeMessage encodes a message in the wire format of a client
+func encodeMessage(enc Encoding, msg Message) []byte {
+	data, err := enc.Encode(msg)
 	if err != nil {
 		logger.Logger.Error("[EncodeError] Failed to encode message",
 			"type", msg.Type,
+			"encoding", enc.Subprotocol(),
 			"error", err)
-		return []byte(`{"type":"error","content":"Internal server error"}`)
+		data, _ = enc.Encode(Message{Type: ErrorMessage, Content: json.RawMessage(`"Internal server error"`)})
 	}
 	return data
 }
@@ -61,33 +85,19 @@ func encodeMessage(msg Message) []byte {
 func messageHandeling(message Message, h *Hub) {
 	switch message.Type {
 	case BroadcastMessage:
-		// message broadcasting to all clients
-		for client := range h.clients {
-			if client.name == message.Sender {
-				continue
-			} // skip the broadcast to sender
-			client.send <- encodeMessage(message)
-		}
+		// message broadcasting to all clients, skipping the sender
+		h.broadcastExcept(message, message.Sender)
 
 	case PrivateMessage:
 		// message for specific client
-		for client := range h.clients {
-			if client.name == message.Reciever {
-				client.send <- encodeMessage(message)
-				break
-			}
-		}
+		h.sendToUser(message.Reciever, message)
 
 	case SystemMessage:
 		// message for all clients
-		for client := range h.clients {
-			client.send <- encodeMessage(message)
-		}
+		h.broadcastAll(message)
 
 	case PlayerListMessage:
-		for client := range h.clients {
-			client.send <- encodeMessage(message)
-		}
+		h.broadcastAll(message)
 
 	case PlayerReadyToggle:
 		logger.Logger.Debug("[Game] ready_toggle received", "sender", message.Sender)
@@ -95,7 +105,7 @@ func messageHandeling(message Message, h *Hub) {
 		var contentStr string
 		if err := json.Unmarshal(message.Content, &contentStr); err == nil {
 			for client := range h.clients {
-				if client.name == message.Sender {
+				if client.id == message.Sender && !client.spectator {
 					if contentStr == "ready" {
 						client.status = StatusReady
 					} else {
@@ -114,7 +124,7 @@ func messageHandeling(message Message, h *Hub) {
 	case ResetReady:
 		logger.Logger.Debug("[Game] reset_ready received", "sender", message.Sender)
 		for client := range h.clients {
-			if client.name == message.Sender {
+			if client.id == message.Sender && !client.spectator {
 				client.status = StatusIdle
 				break
 			}
@@ -122,6 +132,10 @@ func messageHandeling(message Message, h *Hub) {
 		h.BroadcastPlayerList()
 		// Also reset the game state so a new round can start
 		h.ResetGameState()
+		// Back to lobby once nobody is racing anymore
+		if !h.anyoneInGame() {
+			h.setState(RoomStateLobby)
+		}
 
 	case PlayerJoinedGame:
 		// A client has arrived on the game page. Track it and start
@@ -133,64 +147,75 @@ func messageHandeling(message Message, h *Hub) {
 		h.PlayerJoinedGame(message.Sender)
 
 	case PlayerProgress:
-		// Relay player progress (cursor position, WPM) to all OTHER clients in the room
+		// Verify the submission with the race engine and broadcast the
+		// server computed position / WPM / accuracy
 		logger.Logger.Debug("[Game] player_progress received",
 			"sender", message.Sender,
 			"room_id", h.roomId,
 		)
-		for client := range h.clients {
-			if client.name == message.Sender {
-				continue
-			}
-			client.send <- encodeMessage(message)
-		}
+		h.handleProgress(message)
 
 	case GameFinished:
-		// Relay game finished notification to all OTHER clients in the room
+		// Finishing is decided by the race engine, the client claim is
+		// only relayed when no server side race exists
 		logger.Logger.Info("[Game] game_finished received",
 			"sender", message.Sender,
 			"room_id", h.roomId,
 		)
-		for client := range h.clients {
-			if client.name == message.Sender {
-				continue
-			}
-			client.send <- encodeMessage(message)
-		}
+		h.handleGameFinished(message)
 
 	case GameStart:
-		// Broadcast game start (with text) to ALL clients including sender
-		logger.Logger.Info("[Game] game_start received",
+		// A client's game_start is a request to start the round, only the
+		// host may do that. Its content is ignored: the round text is
+		// generated by the hub (see startRound), a client must never be able
+		// to push its own text to the room
+		logger.Logger.Info("[Game] game_start requested",
 			"sender", message.Sender,
 			"room_id", h.roomId,
 		)
-		for client := range h.clients {
-			client.send <- encodeMessage(message)
-		}
+		h.handleStartRequest(message)
+
+	case RoomSettingsMessage:
+		h.handleSettingsUpdate(message)
+
+	case KeystrokesMessage:
+		// Optional telemetry, kept until the race is over
+		h.handleKeystrokes(message)
+
+	case TeamAssignMessage:
+		h.handleTeamAssign(message)
+
+	case TeamBalanceMessage:
+		h.handleTeamBalance(message)
+
+	case CloseRoomMessage:
+		logger.Logger.Info("[Room] close_room requested",
+			"sender", message.Sender,
+			"room_id", h.roomId,
+		)
+		h.handleCloseRequest(message)
 
 	case GameCountdown:
-		// Server-generated countdown tick (3, 2, 1) — send to ALL clients
+		// Server-generated start of the round — send to ALL clients
 		logger.Logger.Debug("[Game] game_countdown",
 			"room_id", h.roomId,
 		)
-		for client := range h.clients {
-			client.send <- encodeMessage(message)
-		}
+		h.broadcastAll(message)
 
 	case GameGo:
 		// Server-generated GO signal with start_time — send to ALL clients
 		logger.Logger.Info("[Game] game_go",
 			"room_id", h.roomId,
 		)
-		for client := range h.clients {
-			client.send <- encodeMessage(message)
-		}
+		h.broadcastAll(message)
 		// NOW mark all players as "in_game". At this point every player
 		// has arrived on the game page and the race is truly starting.
 		// This prevents a returning-to-lobby player from triggering a
 		// new game while others are still racing.
 		for client := range h.clients {
-			client.status = StatusInGame
+			if !client.spectator {
+				client.status = StatusInGame
+			}
 		}
 		h.BroadcastPlayerList()
 	}
@@ -198,11 +223,25 @@ func messageHandeling(message Message, h *Hub) {
 
 // Validates the received message based on its type
 func ValidateMessage(msg *Message) error {
+	switch {
+	case msg.protocol == 0:
+		// Sent by the hub itself, its content is already typed
+		if _, ok := messageKinds[msg.Type]; !ok {
+			return fmt.Errorf("invalid message type: %s", msg.Type)
+		}
+		return nil
+	case messageKinds[msg.Type] == contentServer:
+		// Whatever the protocol, a client never speaks for the server
+		return fmt.Errorf("%s messages are sent by the server only", msg.Type)
+	case msg.protocol >= ProtocolV2:
+		return validateStrict(msg)
+	}
+
+	// Protocol v1, as lenient as it always was
 	switch msg.Type {
 
-	// Chat / system messages — content must be a JSON string
-	case BroadcastMessage, SystemMessage, PrivateMessage,
-		PlayerListMessage, PlayerReadyToggle:
+	// Chat messages — content must be a JSON string
+	case BroadcastMessage, PrivateMessage, PlayerReadyToggle:
 		var content string
 		if err := json.Unmarshal(msg.Content, &content); err != nil {
 			return fmt.Errorf("invalid message content format: %v", err)
@@ -212,18 +251,15 @@ func ValidateMessage(msg *Message) error {
 		}
 
 	// Request / signal messages — content can be empty or minimal
-	case RequestPlayerList, ResetReady, PlayerJoinedGame:
+	case RequestPlayerList, ResetReady, PlayerJoinedGame, GameStart, CloseRoomMessage,
+		TeamBalanceMessage:
 		// These are simple signal messages; no strict content validation needed
 		// beyond being valid JSON (which is already guaranteed by unmarshal in ReadPump)
 
-	// Server-originated messages (countdown / go) — should never arrive FROM a client,
-	// but if they do just let them through validation so the hub can ignore them.
-	case GameCountdown, GameGo:
-		// no-op: these are server-generated
-
 	// Game messages — content is a JSON object (or JSON-encoded string of an object)
 	// We just verify it's non-empty valid JSON
-	case PlayerProgress, GameFinished, GameStart:
+	case PlayerProgress, GameFinished, RoomSettingsMessage, KeystrokesMessage,
+		TeamAssignMessage:
 		if len(msg.Content) == 0 {
 			return fmt.Errorf("empty game message content")
 		}
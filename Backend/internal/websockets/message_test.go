@@ -0,0 +1,39 @@
+package websockets
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// A client of any protocol must not be able to send what only the server
+// sends, the hub would act on it
+func TestValidateServerOnly(t *testing.T) {
+	tests := []struct {
+		typ     string
+		content json.RawMessage
+	}{
+		{GameGo, json.RawMessage(`{"start_time":0}`)},
+		{GameCountdown, json.RawMessage(`3`)},
+		{PlayerListMessage, json.RawMessage(`"[]"`)},
+		{SystemMessage, json.RawMessage(`"Everyone won"`)},
+		{RaceResultsMessage, json.RawMessage(`{}`)},
+	}
+	for _, tt := range tests {
+		for _, protocol := range []int{ProtocolV1, ProtocolV2} {
+			msg := Message{Type: tt.typ, Content: tt.content, protocol: protocol}
+			if err := ValidateMessage(&msg); err == nil {
+				t.Errorf("%s from a v%d client accepted", tt.typ, protocol)
+			}
+		}
+		// The hub itself sends them
+		msg := Message{Type: tt.typ, Content: tt.content}
+		if err := ValidateMessage(&msg); err != nil {
+			t.Errorf("%s from the hub: %v", tt.typ, err)
+		}
+	}
+
+	msg := Message{Type: BroadcastMessage, Content: json.RawMessage(`"hi"`), protocol: ProtocolV1}
+	if err := ValidateMessage(&msg); err != nil {
+		t.Errorf("chat from a v1 client: %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// This file defines the versioned message protocol spoken between the hub
+// and its clients. Every message type has a typed payload as content.
+//
+// Protocol v1 is what clients spoke before the protocol was versioned: the
+// payload of object messages travels as a JSON string holding the object,
+// so clients have to JSON.parse the content a second time, and content is
+// decoded leniently. From v2 on the content is the payload itself and
+// what a client sends is decoded strictly: unknown fields are rejected, so
+// are string-wrapped objects and messages only the server may send.
+//
+// A client asks for a version with ?protocol=N when it connects and the
+// session message tells which one it got. Clients that don't ask speak v1,
+// so they keep working while they migrate. Inside the hub messages always
+// hold the v2 content, v1 content is converted when it comes in and goes
+// out.
+//
+// The message types and their payloads are listed in messageSpecs, the
+// schema and the TypeScript types of the frontend are generated from it.
+
+//go:generate go run ../../cmd/protogen -schema protocol.schema.json -ts ../../../Frontend/src/lib/protocol.ts
+
+package websockets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/corpus"
+	"github.com/ManogyaDahal/GoType/internal/race"
+)
+
+// Protocol versions
+const (
+	ProtocolV1     = 1 // payloads wrapped in JSON strings, lenient decoding
+	ProtocolV2     = 2 // payloads as is, strict decoding
+	LatestProtocol = ProtocolV2
+)
+
+// ErrUnsupportedProtocol is returned for a protocol version that doesn't exist
+var ErrUnsupportedProtocol = errors.New("unsupported protocol version")
+
+// NegotiateProtocol returns the protocol version spoken with a client that
+// asked for requested (the ?protocol= query parameter). No version is a
+// client from before versioning, a version newer than the server's gets
+// the latest one the server speaks.
+func NegotiateProtocol(requested string) (int, error) {
+	if requested == "" {
+		return ProtocolV1, nil
+	}
+	v, err := strconv.Atoi(requested)
+	if err != nil || v < ProtocolV1 {
+		return 0, ErrUnsupportedProtocol
+	}
+	if v > LatestProtocol {
+		return LatestProtocol, nil
+	}
+	return v, nil
+}
+
+// Payloads of the messages whose type is declared elsewhere
+type (
+	// ProgressPayload is the content of the player_progress and
+	// game_finished messages the server sends
+	ProgressPayload = race.Progress
+	// InputPayload is the content of a player_progress message sent by a
+	// client
+	InputPayload = race.Input
+	// KeystrokesPayload is the content of a keystrokes message
+	KeystrokesPayload = analytics.Batch
+	// SettingsPayload is the content of a room_settings message
+	SettingsPayload = RoomSettings
+	// QuotePayload is the content of a text_source message in quote mode
+	QuotePayload = corpus.Quote
+)
+
+// PlayerInfo is an entry of the player_list message
+type PlayerInfo struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Ready  bool   `json:"ready"` // status == ready, kept for older clients
+	Status string `json:"status"`
+	Host   bool   `json:"host"`
+	Team   int    `json:"team"` // 0 when teams are off
+}
+
+// PlayerListPayload is the content of a player_list message
+type PlayerListPayload []PlayerInfo
+
+// SpectatorListPayload is the content of a spectator_list message
+type SpectatorListPayload []SpectatorInfo
+
+// GoPayload is the content of a game_go message, times in Unix ms
+type GoPayload struct {
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time,omitempty"` // deadline of a timed round
+}
+
+// FinishPayload is the content of a game_finished message sent by a
+// client: the result it claims, never trusted
+type FinishPayload struct {
+	WPM float64 `json:"wpm"`
+}
+
+// wrappedInV1 lists the message types whose payload travels as a JSON
+// string in protocol v1, in both directions
+var wrappedInV1 = map[string]bool{
+	SessionMessage:           true,
+	PlayerListMessage:        true,
+	SpectatorListMessage:     true,
+	PlayerProgress:           true,
+	GameFinished:             true,
+	RaceResultsMessage:       true,
+	TextSourceMessage:        true,
+	PlayerEliminatedMessage:  true,
+	EliminationWinnerMessage: true,
+	RoomSettingsMessage:      true,
+	HostChanged:              true,
+	RoomClosed:               true,
+	KeystrokesMessage:        true,
+	TeamAssignMessage:        true,
+}
+
+// forProtocol returns the message as a client speaking protocol receives it
+func (m Message) forProtocol(protocol int) Message {
+	if protocol != ProtocolV1 || !wrappedInV1[m.Type] || len(m.Content) == 0 {
+		return m
+	}
+	if wrapped, err := json.Marshal(string(m.Content)); err == nil {
+		m.Content = wrapped
+	}
+	return m
+}
+
+// fromProtocol returns a message received from a client speaking protocol
+// with the content the hub works with
+func (m Message) fromProtocol(protocol int) Message {
+	m.protocol = protocol
+	if protocol != ProtocolV1 || !wrappedInV1[m.Type] {
+		return m
+	}
+	var inner string
+	if err := json.Unmarshal(m.Content, &inner); err == nil && json.Valid([]byte(inner)) {
+		m.Content = json.RawMessage(inner)
+	}
+	return m
+}
+
+// encode encodes a message for the client
+func (c *Clients) encode(msg Message) []byte {
+	return encodeMessage(c.encoding, msg.forProtocol(c.protocol))
+}
+
+// decodeMessage decodes a message received in enc from a client speaking
+// protocol. From v2 on, unknown fields are rejected.
+func decodeMessage(enc Encoding, data []byte, protocol int) (Message, error) {
+	msg, err := enc.Decode(data, protocol >= ProtocolV2)
+	if err != nil {
+		return Message{}, err
+	}
+	return msg.fromProtocol(protocol), nil
+}
+
+// encodeContent marshals the payload of a message
+func encodeContent(v any) (json.RawMessage, error) {
+	return json.Marshal(v)
+}
+
+// decodeContent unmarshals the content of a message received from a
+// client into its payload. The content of a v2 client must match the
+// payload exactly.
+func (m Message) decodeContent(v any) error {
+	return decodeJSON(m.Content, v, m.protocol >= ProtocolV2)
+}
+
+// decodeJSON unmarshals data into v, strict rejects unknown fields and
+// trailing data
+func decodeJSON(data []byte, v any, strict bool) error {
+	if !strict {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// Signal is the content of the messages whose content is ignored
+type Signal = json.RawMessage
+
+// AnyOf is the content of the messages whose content has several shapes
+type AnyOf []any
+
+// MessageSpec describes one message type of the protocol: what it is for
+// and the content each side sends. The list of specs is the source of
+// truth of the protocol, strict validation checks client messages against
+// it and cmd/protogen generates the JSON Schema and the TypeScript types
+// of the frontend from it.
+type MessageSpec struct {
+	Type   string
+	Doc    string
+	Client any // zero value of the content clients send, nil when they never do
+	Server any // zero value of the content the server sends, nil when it never does
+}
+
+// messageSpecs is the protocol, "" stands for string content
+var messageSpecs = []MessageSpec{
+	{Type: BroadcastMessage, Doc: "Chat message to everybody in the room", Client: "", Server: ""},
+	{Type: PrivateMessage, Doc: "Chat message to the user in reciever", Client: "", Server: ""},
+	{Type: SystemMessage, Doc: "Notice about the room, e.g. a user joined", Server: ""},
+	{Type: PlayerListMessage, Doc: "Players of the room with their status", Server: PlayerListPayload{}},
+	{Type: PlayerReadyToggle, Doc: `Player readiness, "ready" or "not ready"`, Client: ""},
+	{Type: PlayerProgress, Doc: "Typed word or keys, answered with the verified progress of the player", Client: InputPayload{}, Server: ProgressPayload{}},
+	{Type: GameFinished, Doc: "A player finished; clients send the result they claim", Client: FinishPayload{}, Server: ProgressPayload{}},
+	{Type: GameStart, Doc: "Host asks to start the round, the server answers with the text of the round", Client: Signal{}, Server: ""},
+	{Type: RequestPlayerList, Doc: "Asks for a fresh player_list", Client: Signal{}},
+	{Type: ResetReady, Doc: "Sets the sender back to not ready", Client: Signal{}},
+	{Type: PlayerJoinedGame, Doc: "The client arrived on the game page", Client: Signal{}},
+	{Type: GameCountdown, Doc: "The host started the round, the content is the seconds to count down before the game page", Server: 0},
+	{Type: GameGo, Doc: "The race starts at start_time", Server: GoPayload{}},
+	{Type: RaceResultsMessage, Doc: "Final standings once the race is over", Server: ResultsPayload{}},
+	{Type: KeystrokesMessage, Doc: "Batch of key presses, optional telemetry", Client: KeystrokesPayload{}},
+	{Type: TextSourceMessage, Doc: "Where the text of the round is from: a quote or a code snippet", Server: AnyOf{QuotePayload{}, CodeSourcePayload{}}},
+	{Type: PlayerEliminatedMessage, Doc: "A player is out of the elimination match", Server: EliminatedPayload{}},
+	{Type: EliminationWinnerMessage, Doc: "The last player standing won the elimination match", Server: WinnerPayload{}},
+	{Type: SessionMessage, Doc: "Resumable session of the connection and the negotiated protocol version", Server: SessionPayload{}},
+	{Type: ErrorMessage, Doc: "A request was refused, the content is the reason", Server: ""},
+	{Type: RoomSettingsMessage, Doc: "Host changes the settings, the server sends the current ones", Client: SettingsPayload{}, Server: SettingsPayload{}},
+	{Type: HostChanged, Doc: "The host role moved to another player", Server: HostPayload{}},
+	{Type: SpectatorListMessage, Doc: "Spectators watching the room", Server: SpectatorListPayload{}},
+	{Type: CloseRoomMessage, Doc: "Host closes the room for everyone", Client: Signal{}},
+	{Type: TeamAssignMessage, Doc: "Host moves a player to a team", Client: TeamAssignPayload{}},
+	{Type: TeamBalanceMessage, Doc: "Host has the teams balanced by rating", Client: Signal{}},
+	{Type: RoomClosed, Doc: "The room was closed", Server: ClosedPayload{}},
+}
+
+// Messages returns the specs of every message type of the protocol
+func Messages() []MessageSpec {
+	return append([]MessageSpec(nil), messageSpecs...)
+}
+
+// contentKind is what the content of a message sent by a client holds
+type contentKind int
+
+const (
+	contentText    contentKind = iota + 1 // a non-empty JSON string
+	contentPayload                        // a JSON object
+	contentSignal                         // anything, ignored
+	contentServer                         // sent by the server only
+)
+
+// messageKinds gives the content kind of every message type
+var messageKinds = kindsOf(messageSpecs)
+
+func kindsOf(specs []MessageSpec) map[string]contentKind {
+	kinds := make(map[string]contentKind, len(specs))
+	for _, spec := range specs {
+		switch spec.Client.(type) {
+		case nil:
+			kinds[spec.Type] = contentServer
+		case string:
+			kinds[spec.Type] = contentText
+		case Signal:
+			kinds[spec.Type] = contentSignal
+		default:
+			kinds[spec.Type] = contentPayload
+		}
+	}
+	return kinds
+}
+
+// validateStrict validates a message sent by a client speaking v2 or later
+func validateStrict(msg *Message) error {
+	kind, ok := messageKinds[msg.Type]
+	if !ok {
+		return fmt.Errorf("invalid message type: %s", msg.Type)
+	}
+	switch kind {
+	case contentText:
+		var content string
+		if err := decodeJSON(msg.Content, &content, true); err != nil {
+			return fmt.Errorf("content of %s must be a string: %v", msg.Type, err)
+		}
+		if len(bytes.TrimSpace([]byte(content))) == 0 {
+			return fmt.Errorf("empty message content")
+		}
+	case contentPayload:
+		if t := bytes.TrimSpace(msg.Content); len(t) == 0 || t[0] != '{' || !json.Valid(t) {
+			return fmt.Errorf("content of %s must be a JSON object", msg.Type)
+		}
+	case contentServer:
+		return fmt.Errorf("%s messages are sent by the server only", msg.Type)
+	}
+	return nil
+}
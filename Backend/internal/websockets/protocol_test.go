@@ -0,0 +1,168 @@
+package websockets_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/protogen"
+	"github.com/ManogyaDahal/GoType/internal/websockets"
+)
+
+const (
+	schemaFile = "protocol.schema.json"
+	tsFile     = "../../../Frontend/src/lib/protocol.ts"
+)
+
+// handledTypes returns the message types of the cases of the
+// messageHandeling switch, read from the source of message.go
+func handledTypes(t *testing.T) map[string]bool {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "message.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consts := map[string]string{}
+	var handler *ast.FuncDecl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range d.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						consts[name.Name] = constant.StringVal(constant.MakeFromLiteral(lit.Value, lit.Kind, 0))
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Name.Name == "messageHandeling" {
+				handler = d
+			}
+		}
+	}
+	if handler == nil {
+		t.Fatal("messageHandeling not found in message.go")
+	}
+
+	types := map[string]bool{}
+	ast.Inspect(handler.Body, func(n ast.Node) bool {
+		clause, ok := n.(*ast.CaseClause)
+		if !ok {
+			return true
+		}
+		for _, expr := range clause.List {
+			switch e := expr.(type) {
+			case *ast.Ident:
+				value, ok := consts[e.Name]
+				if !ok {
+					t.Errorf("%s: case %s is not a message type constant", fset.Position(e.Pos()), e.Name)
+				}
+				types[value] = true
+			case *ast.BasicLit:
+				value, _ := strconv.Unquote(e.Value)
+				types[value] = true
+			default:
+				t.Errorf("%s: unexpected case expression", fset.Position(e.Pos()))
+			}
+		}
+		return true
+	})
+	return types
+}
+
+// schemaTypes returns the message types of the ClientMessage and
+// ServerMessage definitions of the schema file
+func schemaTypes(t *testing.T) (client, server map[string]bool) {
+	t.Helper()
+	data, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type alternatives struct {
+		OneOf []struct {
+			Properties struct {
+				Type struct {
+					Const string `json:"const"`
+				} `json:"type"`
+			} `json:"properties"`
+		} `json:"oneOf"`
+	}
+	var schema struct {
+		Defs struct {
+			Client alternatives `json:"ClientMessage"`
+			Server alternatives `json:"ServerMessage"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(alts alternatives) map[string]bool {
+		types := map[string]bool{}
+		for _, alt := range alts.OneOf {
+			types[alt.Properties.Type.Const] = true
+		}
+		return types
+	}
+	return collect(schema.Defs.Client), collect(schema.Defs.Server)
+}
+
+// TestHandlerMatchesSchema fails when a message type is handled by the hub
+// but missing from the schema, or a client may send a type the hub
+// doesn't handle
+func TestHandlerMatchesSchema(t *testing.T) {
+	handled := handledTypes(t)
+	client, server := schemaTypes(t)
+
+	for typ := range handled {
+		if !client[typ] && !server[typ] {
+			t.Errorf("messageHandeling handles %q, the schema doesn't define it", typ)
+		}
+	}
+	for typ := range client {
+		if !handled[typ] {
+			t.Errorf("clients may send %q, messageHandeling has no case for it", typ)
+		}
+	}
+}
+
+// TestGeneratedFilesUpToDate fails when the message specs changed without
+// running go generate
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	outputs := []struct {
+		path     string
+		generate func([]websockets.MessageSpec) ([]byte, error)
+	}{
+		{schemaFile, protogen.Schema},
+		{tsFile, protogen.TypeScript},
+	}
+	for _, out := range outputs {
+		want, err := out.generate(websockets.Messages())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(out.path)
+		if os.IsNotExist(err) && out.path == tsFile {
+			t.Logf("%s not found, skipped", tsFile)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate ./internal/websockets", out.path)
+		}
+	}
+}
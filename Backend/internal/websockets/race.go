@@ -0,0 +1,388 @@
+// This file wires the race engine into the hub. The hub owns one race per
+// round and every progress / finish message broadcast to the room is
+// computed by the engine instead of being relayed from the client.
+
+package websockets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/corpus"
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/race"
+	"github.com/ManogyaDahal/GoType/internal/replay"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+	"github.com/ManogyaDahal/GoType/internal/text"
+)
+
+// startRound picks the text for the next round and sends it to every
+// client in a game_start message. The text is seeded per round, so a
+// finished race can be regenerated from its seed. A room with a ghost
+// races the text of the ghost's race instead.
+func (h *Hub) startRound() {
+	h.round++
+	h.quote = nil
+	h.snippet = nil
+	if h.loadGhost() {
+		h.textSeed = "replay:" + h.settings.Ghost.RaceID
+		h.raceText = h.ghost.text
+	} else {
+		h.textSeed = fmt.Sprintf("%s#%d", h.roomId, h.round)
+		h.raceText = h.pickText()
+	}
+
+	content, _ := json.Marshal(h.raceText)
+	msg := Message{
+		Type:      GameStart,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	}
+	h.broadcastAll(msg)
+	switch {
+	case h.quote != nil:
+		h.broadcastTextSource(h.quote)
+	case h.snippet != nil:
+		h.broadcastTextSource(CodeSourcePayload{Snippet: *h.snippet, Indentation: h.settings.Indentation})
+	}
+
+	logger.Logger.Info("[Race] Round text generated",
+		"roomId", h.roomId,
+		"round", h.round,
+		"seed", h.textSeed,
+		"words", h.settings.WordCount,
+	)
+}
+
+// pickText returns the text of the round for the room's mode
+func (h *Hub) pickText() string {
+	switch h.settings.Mode {
+	case ModeTimed:
+		return text.GenerateTimed(h.textSeed, h.settings.Language, h.settings.Layout, h.settings.Duration)
+	case ModeQuote:
+		if q, ok := corpus.Default.Pick(h.settings.Language, h.settings.QuoteLength, h.textSeed); ok {
+			h.quote = &q
+			return q.Text
+		}
+		// Checked by Normalize, only reachable if the corpus changed
+		logger.Logger.Warn("[Race] No quote available, generating words",
+			"roomId", h.roomId,
+			"language", h.settings.Language,
+			"length", h.settings.QuoteLength,
+		)
+	case ModeCustom:
+		if strings.TrimSpace(h.settings.CustomText) != "" {
+			return h.settings.CustomText
+		}
+		// Checked by Normalize, only reachable if the settings skipped it
+		logger.Logger.Warn("[Race] No custom text, generating words",
+			"roomId", h.roomId,
+		)
+	case ModeCode:
+		if s, ok := corpus.DefaultCode.Pick(h.settings.CodeLanguage, h.textSeed); ok {
+			h.snippet = &s
+			return s.Text
+		}
+		// Checked by Normalize, only reachable if the snippets changed
+		logger.Logger.Warn("[Race] No snippet available, generating words",
+			"roomId", h.roomId,
+			"codeLanguage", h.settings.CodeLanguage,
+		)
+	}
+	return text.GenerateLanguage(h.textSeed, h.settings.Language, h.settings.Layout, h.settings.WordCount)
+}
+
+// CodeSourcePayload is the content of a text_source message in code mode
+type CodeSourcePayload struct {
+	corpus.Snippet
+	Indentation Indentation `json:"indentation"`
+}
+
+// broadcastTextSource tells the room where the text of the round is from:
+// the quote, or the snippet and how its indentation is typed
+func (h *Hub) broadcastTextSource(source any) {
+	content, err := encodeContent(source)
+	if err != nil {
+		logger.Logger.Error("[Race] Failed to encode text source", "error", err)
+		return
+	}
+	h.broadcastAll(Message{
+		Type:      TextSourceMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
+
+// startRace creates the authoritative race for the round that starts at
+// startTime, enrolls every connected player as a participant, arms the
+// round's time limit and starts the ghost. A timed round stops accepting
+// input at its deadline.
+func (h *Hub) startRace(startTime time.Time) {
+	var err error
+	switch {
+	case h.settings.Mode == ModeTimed:
+		h.race, err = race.NewTimed(h.raceText, startTime, h.settings.roundLimit())
+	case h.snippet != nil:
+		h.race, err = race.NewCode(h.raceText, startTime, h.settings.Indentation == IndentAuto)
+	default:
+		h.race, err = race.New(h.raceText, startTime)
+	}
+	if err != nil {
+		// pickText never returns an empty text, progress is ignored
+		// without a race anyway
+		logger.Logger.Error("[Race] Failed to create race", "roomId", h.roomId, "error", err)
+		h.race = nil
+		return
+	}
+	h.raceRecorded = false
+	h.keystrokes = make(map[string][]analytics.Keystroke)
+	h.watches = make(map[string]*playerWatch)
+	h.replay = replay.NewRecorder(h.raceText, string(h.settings.Mode), h.settings.Language, startTime)
+	var players map[string]bool
+	if h.settings.Mode == ModeElimination && h.survivors == nil {
+		// The first round of the match decides who plays it
+		players = make(map[string]bool)
+	}
+	for client := range h.clients {
+		if h.inMatch(client) {
+			h.race.Join(client.id, client.name)
+			if players != nil {
+				players[client.id] = true
+			}
+		}
+	}
+	if players != nil {
+		h.survivors = players
+	}
+	h.scheduleRaceTimeout(startTime)
+	h.startGhost(startTime)
+	logger.Logger.Info("[Race] Race created",
+		"roomId", h.roomId,
+		"players", h.playerCount(),
+		"length", h.race.Length(),
+	)
+}
+
+// handleProgress verifies a player_progress submission and broadcasts the
+// resulting authoritative progress to the room.
+func (h *Hub) handleProgress(message Message) {
+	if h.race == nil {
+		logger.Logger.Debug("[Race] Progress received but no race is running",
+			"sender", message.Sender,
+			"roomId", h.roomId,
+		)
+		return
+	}
+
+	var input InputPayload
+	if err := message.decodeContent(&input); err != nil {
+		logger.Logger.Warn("[Race] Invalid progress payload",
+			"sender", message.Sender,
+			"roomId", h.roomId,
+			"error", err,
+		)
+		return
+	}
+
+	if h.race.Player(message.Sender) == nil {
+		// Joined after the race started, they take part in the next round
+		return
+	}
+
+	now := time.Now()
+	h.watchInput(message.Sender, input)
+	wasFinished := h.race.Player(message.Sender).Finished
+	progress, err := h.race.Submit(message.Sender, input, now)
+	switch {
+	case errors.Is(err, race.ErrWordOutOfOrder):
+		// Still broadcast, the cursor hint may have moved
+		logger.Logger.Warn("[Race] Word submitted out of order",
+			"sender", message.Sender,
+			"roomId", h.roomId,
+		)
+	case err != nil:
+		logger.Logger.Debug("[Race] Submission rejected",
+			"sender", message.Sender,
+			"roomId", h.roomId,
+			"error", err,
+		)
+		return
+	}
+	h.watchPosition(message.Sender, now)
+	// The replay follows the verified position, Pos is the client's cursor hint
+	h.replay.Add(progress.ID, progress.Name, h.race.Player(message.Sender).Position, progress.WPM, now)
+
+	h.broadcastRaceMessage(PlayerProgress, progress)
+
+	if progress.Finished && !wasFinished {
+		logger.Logger.Info("[Race] Player finished",
+			"player", message.Sender,
+			"place", progress.Place,
+			"wpm", progress.WPM,
+			"roomId", h.roomId,
+		)
+		h.broadcastRaceMessage(GameFinished, progress)
+	}
+
+	if h.race.Finished() && !h.raceRecorded {
+		h.finishRace(RaceEndFinished)
+	}
+}
+
+// abandonRace stops waiting for a player whose session expired, unless they
+// are still connected another way. Nobody else can end the race for them:
+// it is over once the other participants are done.
+func (h *Hub) abandonRace(userID string) {
+	if h.race == nil || h.race.Player(userID) == nil {
+		return
+	}
+	for client := range h.clients {
+		if client.id == userID {
+			return
+		}
+	}
+	h.race.Abandon(userID)
+	logger.Logger.Info("[Race] Player left the race",
+		"player", userID,
+		"roomId", h.roomId,
+	)
+	if h.race.Finished() && !h.raceRecorded {
+		h.finishRace(RaceEndFinished)
+	}
+}
+
+// recordRace stores the finished race with the result of every player and
+// its replay, and updates their skill ratings. Saving happens off the Run
+// loop so disk I/O never stalls the room.
+func (h *Hub) recordRace(results []RaceResult, now time.Time) {
+	h.raceRecorded = true
+	if h.store == nil {
+		return
+	}
+
+	record := storage.Race{
+		ID:         storage.NewID(),
+		RoomID:     h.roomId,
+		Round:      h.round,
+		Seed:       h.textSeed,
+		Text:       h.race.Text(),
+		WordCount:  h.settings.WordCount,
+		Mode:       string(h.settings.Mode),
+		Language:   h.settings.Language,
+		Verified:   true,
+		Unranked:   h.settings.Mode == ModeCustom || h.ghost != nil,
+		StartedAt:  h.race.StartTime(),
+		FinishedAt: now,
+	}
+	switch {
+	case h.race.Timed():
+		// Timed races compare by duration, the length of the text is arbitrary
+		record.WordCount = 0
+		record.Duration = h.settings.Duration
+	case h.settings.Mode == ModeQuote || h.settings.Mode == ModeCustom || h.settings.Mode == ModeCode:
+		record.WordCount = len(strings.Fields(record.Text))
+	}
+	if h.quote != nil {
+		record.QuoteID = h.quote.ID
+	}
+	if h.snippet != nil {
+		record.SnippetID = h.snippet.ID
+	}
+	finished := make(map[string]bool, len(results))
+	for _, r := range results {
+		finished[r.ID] = !r.DNF
+		record.Results = append(record.Results, storage.Result{
+			UserID:   r.ID,
+			Name:     r.Name,
+			Place:    r.Place,
+			WPM:      r.WPM,
+			Accuracy: r.Accuracy,
+			TimeMs:   r.TimeMs,
+			Finished: !r.DNF,
+			Flagged:  r.Flagged,
+			Team:     r.Team,
+		})
+	}
+
+	rep := h.replay.Replay(record.ID, finished)
+
+	store, ratings := h.store, h.ratings
+	go func() {
+		if err := store.SaveRace(record); err != nil {
+			logger.Logger.Error("[Race] Failed to save race",
+				"roomId", record.RoomID,
+				"round", record.Round,
+				"error", err,
+			)
+			return
+		}
+		logger.Logger.Info("[Race] Race recorded",
+			"roomId", record.RoomID,
+			"round", record.Round,
+			"raceId", record.ID,
+		)
+
+		if err := store.SaveReplay(rep); err != nil {
+			logger.Logger.Error("[Race] Failed to save replay",
+				"raceId", record.ID,
+				"error", err,
+			)
+		}
+
+		if ratings == nil {
+			return
+		}
+		changes, err := ratings.Apply(record)
+		if err != nil {
+			logger.Logger.Error("[Race] Failed to update ratings",
+				"raceId", record.ID,
+				"error", err,
+			)
+			return
+		}
+		if len(changes) > 0 {
+			logger.Logger.Info("[Race] Ratings updated",
+				"raceId", record.ID,
+				"players", len(changes),
+			)
+		}
+	}()
+}
+
+// handleGameFinished deals with a client claiming it finished. The claim
+// is ignored, the engine decides when a player is done, but an impossible
+// claim is noted by the anti-cheat.
+func (h *Hub) handleGameFinished(message Message) {
+	h.watchClaim(message.Sender, message)
+	logger.Logger.Debug("[Race] Ignoring client reported game_finished",
+		"sender", message.Sender,
+		"roomId", h.roomId,
+	)
+}
+
+// broadcastRaceMessage sends a server computed race update about a player
+// to every other client (the player renders its own progress locally).
+func (h *Hub) broadcastRaceMessage(msgType string, progress race.Progress) {
+	content, err := encodeContent(progress)
+	if err != nil {
+		logger.Logger.Error("[Race] Failed to encode progress", "error", err)
+		return
+	}
+	msg := Message{
+		Type:       msgType,
+		RoomId:     h.roomId,
+		Sender:     progress.ID,
+		SenderName: progress.Name,
+		Content:    content,
+		TimeStamp:  time.Now(),
+	}
+	h.broadcastExcept(msg, progress.ID)
+}
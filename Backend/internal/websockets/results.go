@@ -0,0 +1,165 @@
+// This file ends a race. A race is over when every participant finished or
+// when the room's time limit (the duration of a timed round) expires; the
+// hub then broadcasts the final standings in a race_results message,
+// records the race and sends the room back to the lobby.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// Why a race ended
+const (
+	RaceEndFinished = "finished" // every participant finished or left
+	RaceEndTimeout  = "timeout"  // the time limit expired
+	RaceEndTimeUp   = "time_up"  // a timed round reached its deadline
+)
+
+// RaceResult is the final result of one participant
+type RaceResult struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Place    int     `json:"place"`
+	WPM      float64 `json:"wpm"`
+	Accuracy float64 `json:"accuracy"`
+	TimeMs   int64   `json:"time_ms,omitempty"`
+	Pos      int     `json:"pos"`     // how far in the text the player got
+	Chars    int     `json:"chars"`   // correctly typed characters
+	DNF      bool    `json:"dnf"`     // did not finish before the time limit, never in timed rounds
+	Flagged  bool    `json:"flagged"` // implausible typing, excluded from rankings
+	Team     int     `json:"team,omitempty"`
+}
+
+// ResultsPayload is the content of a race_results message
+type ResultsPayload struct {
+	Round   int          `json:"round"`
+	Reason  string       `json:"reason"`
+	Results []RaceResult `json:"results"`
+	Teams   []TeamResult `json:"teams,omitempty"` // team standings, team races only
+}
+
+// scheduleRaceTimeout arms the time limit of the current round. The timer
+// only signals the Run loop, which ends the race if that round is still
+// running.
+func (h *Hub) scheduleRaceTimeout(startTime time.Time) {
+	h.stopRaceTimer()
+	round := h.round
+	limit := time.Until(startTime) + h.settings.roundLimit()
+	h.raceTimer = time.AfterFunc(limit, func() {
+		select {
+		case h.raceTimeouts <- round:
+		default:
+		}
+	})
+}
+
+// stopRaceTimer cancels a pending race timeout
+func (h *Hub) stopRaceTimer() {
+	if h.raceTimer != nil {
+		h.raceTimer.Stop()
+		h.raceTimer = nil
+	}
+}
+
+// handleRaceTimeout ends the round when its time limit expired
+func (h *Hub) handleRaceTimeout(round int) {
+	if h.race == nil || h.raceRecorded || round != h.round {
+		return
+	}
+	reason := RaceEndTimeout
+	if h.race.Timed() {
+		reason = RaceEndTimeUp
+	}
+	logger.Logger.Info("[Race] Time limit reached",
+		"roomId", h.roomId,
+		"round", round,
+		"reason", reason,
+	)
+	h.finishRace(reason)
+}
+
+// finishRace broadcasts the final standings, records the race and resets
+// the players for the next round
+func (h *Hub) finishRace(reason string) {
+	h.stopRaceTimer()
+	h.stopGhost()
+	now := time.Now()
+	results := h.raceResults(now)
+	h.judgeRace(results)
+	teams := h.teamResults(results)
+
+	content, err := encodeContent(ResultsPayload{
+		Round:   h.round,
+		Reason:  reason,
+		Results: results,
+		Teams:   teams,
+	})
+	if err != nil {
+		logger.Logger.Error("[Race] Failed to encode race results", "error", err)
+	} else {
+		h.broadcastAll(Message{
+			Type:      RaceResultsMessage,
+			RoomId:    h.roomId,
+			Sender:    "server",
+			Content:   content,
+			TimeStamp: now,
+		})
+	}
+
+	h.recordRace(results, now)
+	h.recordKeystrokes(results, now)
+	h.race = nil
+
+	// An elimination match goes on with the next round by itself
+	next := h.settings.Mode == ModeElimination && h.eliminate(results)
+	if next {
+		h.BroadcastPlayerList()
+	} else {
+		h.backToLobby()
+	}
+
+	logger.Logger.Info("[Race] Race over",
+		"roomId", h.roomId,
+		"round", h.round,
+		"reason", reason,
+		"participants", len(results),
+		"next_round", next,
+	)
+}
+
+// backToLobby sends everybody back to the lobby, idle, for the next round
+func (h *Hub) backToLobby() {
+	for client := range h.clients {
+		if client.status == StatusInGame {
+			client.status = StatusIdle
+		}
+	}
+	h.ResetGameState()
+	h.setState(RoomStateLobby)
+	h.BroadcastPlayerList()
+}
+
+// raceResults returns the standings of the current race. Finishers are
+// placed in finish order, the others after them by how far they got. In a
+// timed round reaching the deadline is the end of the race, nobody is DNF.
+func (h *Hub) raceResults(now time.Time) []RaceResult {
+	standings := h.race.Standings(now)
+	results := make([]RaceResult, 0, len(standings))
+	for i, p := range standings {
+		results = append(results, RaceResult{
+			ID:       p.ID,
+			Name:     p.Name,
+			Place:    i + 1,
+			WPM:      p.WPM,
+			Accuracy: p.Accuracy,
+			TimeMs:   p.TimeMs,
+			Pos:      p.Pos,
+			Chars:    p.Chars,
+			DNF:      !p.Finished && !h.race.Timed(),
+		})
+	}
+	return results
+}
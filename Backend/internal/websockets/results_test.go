@@ -0,0 +1,57 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/race"
+)
+
+// When the time limit expires every participant gets a result, the ones
+// still typing DNF behind the finishers, and the room goes back to the lobby
+func TestRaceResults(t *testing.T) {
+	h := newTestHub(t, DefaultRoomSettings(), "host", "slow", "idle")
+	h.state = RoomStateRacing
+	startTestRace(t, h, "go fast", time.Now().Add(-time.Minute))
+
+	submit(h, "slow", race.Input{Keys: "go"})
+	submit(h, "host", race.Input{Keys: "go fast"})
+	if h.race == nil {
+		t.Fatal("race over while players are still typing")
+	}
+	h.handleRaceTimeout(h.round)
+
+	msgs := received(t, h.clientByID("idle"), RaceResultsMessage)
+	if len(msgs) != 1 {
+		t.Fatalf("%d race_results sent, want 1", len(msgs))
+	}
+	var payload ResultsPayload
+	if err := msgs[0].decodeContent(&payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Reason != RaceEndTimeout || len(payload.Results) != 3 {
+		t.Fatalf("results %+v, want 3 after a timeout", payload)
+	}
+	want := []struct {
+		id  string
+		dnf bool
+		pos int
+	}{{"host", false, 7}, {"slow", true, 2}, {"idle", true, 0}}
+	for i, w := range want {
+		r := payload.Results[i]
+		if r.ID != w.id || r.Place != i+1 || r.DNF != w.dnf || r.Pos != w.pos {
+			t.Errorf("result %d = %+v, want %s placed %d, dnf %v at %d", i, r, w.id, i+1, w.dnf, w.pos)
+		}
+	}
+	if r := payload.Results[0]; r.TimeMs == 0 || r.WPM == 0 || r.Accuracy != 100 {
+		t.Errorf("finisher result %+v, want time, WPM and full accuracy", r)
+	}
+
+	if h.race != nil || h.state != RoomStateLobby {
+		t.Errorf("room %s after the results, want back in the lobby", h.state)
+	}
+	h.handleRaceTimeout(h.round)
+	if msgs := received(t, h.clientByID("idle"), RaceResultsMessage); len(msgs) != 0 {
+		t.Error("results sent twice")
+	}
+}
@@ -0,0 +1,283 @@
+// This file implements room ownership. The creator of a room is its host:
+// only the host can start a round or change the room settings, and the
+// host role moves to the longest connected player when the host leaves.
+
+package websockets
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// LobbyCountdown is the number of seconds the lobby counts down once the
+// host started a round
+const LobbyCountdown = 3
+
+// RoomState tells what a room is currently doing
+type RoomState string
+
+const (
+	RoomStateLobby  RoomState = "lobby"  // players are in the lobby, joinable
+	RoomStateRacing RoomState = "racing" // a round is running
+)
+
+// RoomInfo is a snapshot of a room that is safe to read from any goroutine
+// (the room browser and REST handlers use it)
+type RoomInfo struct {
+	ID             string       `json:"room_id"`
+	State          RoomState    `json:"state"`
+	Settings       RoomSettings `json:"settings"`
+	HostID         string       `json:"host_id"`
+	HostName       string       `json:"host_name"`
+	PlayerCount    int          `json:"player_count"`
+	Players        []string     `json:"players"` // display names
+	SpectatorCount int          `json:"spectator_count"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Info returns the latest snapshot of the room
+func (h *Hub) Info() RoomInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	info := h.info
+	info.Players = append([]string(nil), h.info.Players...)
+	return info
+}
+
+// publishInfo refreshes the snapshot returned by Info. Must be called from
+// the Run loop whenever players, settings, host or state change.
+func (h *Hub) publishInfo() {
+	info := RoomInfo{
+		ID:        h.roomId,
+		State:     h.state,
+		Settings:  h.settings,
+		HostID:    h.hostID,
+		Players:   make([]string, 0, len(h.clients)),
+		CreatedAt: h.createdAt,
+	}
+	for client := range h.clients {
+		if client.spectator {
+			info.SpectatorCount++
+			continue
+		}
+		info.Players = append(info.Players, client.name)
+		if client.id == h.hostID {
+			info.HostName = client.name
+		}
+	}
+	info.PlayerCount = len(info.Players)
+
+	h.mu.Lock()
+	h.info = info
+	h.mu.Unlock()
+}
+
+// setState moves the room to a new state
+func (h *Hub) setState(state RoomState) {
+	if h.state == state {
+		return
+	}
+	h.state = state
+	logger.Logger.Info("[Room] State changed", "roomId", h.roomId, "state", state)
+	h.publishInfo()
+}
+
+// HostPayload is the content of a host_changed message
+type HostPayload struct {
+	HostID   string `json:"host_id"`
+	HostName string `json:"host_name"`
+}
+
+// isHost tells if the user owns the room
+func (h *Hub) isHost(userID string) bool {
+	return userID != "" && userID == h.hostID
+}
+
+// canJoin tells if a registering client fits in the room. Players resuming
+// a session or opening a second connection never count as new players.
+// Spectators have their own seats, none when spectating is disabled.
+func (h *Hub) canJoin(c *Clients) bool {
+	if s, ok := h.sessions[c.resumeToken]; ok && s.userID == c.id {
+		return true
+	}
+	for client := range h.clients {
+		if client.id == c.id {
+			return true
+		}
+	}
+	if c.spectator {
+		return h.spectatorCount() < h.settings.MaxSpectators
+	}
+	return h.playerCount() < h.settings.MaxPlayers
+}
+
+// rejectClient refuses a registering client with a reason and closes its
+// connection. The client was never added to the room.
+func (h *Hub) rejectClient(c *Clients, reason string) {
+	content, _ := json.Marshal(reason)
+	c.send <- c.encode(Message{
+		Type:      ErrorMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+	close(c.send)
+	h.EventReport(c, "[hub]", Warning, "Client rejected: "+reason, nil)
+}
+
+// sendError tells a user that a request was refused
+func (h *Hub) sendError(userID string, reason string) {
+	content, _ := json.Marshal(reason)
+	h.sendToUser(userID, Message{
+		Type:      ErrorMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
+
+// handleStartRequest lets the host start the round. Every player in the
+// lobby is marked ready, which starts the lobby countdown on the clients
+// and leads into the usual player_joined_game → game_go flow.
+func (h *Hub) handleStartRequest(message Message) {
+	if !h.isHost(message.Sender) {
+		h.sendError(message.Sender, "Only the host can start the game")
+		return
+	}
+	if h.state == RoomStateRacing {
+		h.sendError(message.Sender, "A round is already running")
+		return
+	}
+	for client := range h.clients {
+		if client.status == StatusIdle {
+			client.status = StatusReady
+		}
+	}
+	h.startRequested = true
+	logger.Logger.Info("[Room] Host started the round",
+		"roomId", h.roomId,
+		"host", message.Sender,
+	)
+	h.BroadcastPlayerList()
+
+	// Clients count down in the lobby, then go to the game page where
+	// their player_joined_game starts the race
+	content, _ := encodeContent(LobbyCountdown)
+	h.broadcastAll(Message{
+		Type:      GameCountdown,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
+
+// handleSettingsUpdate applies new settings sent by the host. They take
+// effect from the next round.
+func (h *Hub) handleSettingsUpdate(message Message) {
+	if !h.isHost(message.Sender) {
+		h.sendError(message.Sender, "Only the host can change the room settings")
+		return
+	}
+	if h.state == RoomStateRacing {
+		h.sendError(message.Sender, "Settings can't be changed during a round")
+		return
+	}
+
+	// Start from the current settings so partial updates are possible
+	settings := h.settings
+	if err := message.decodeContent(&settings); err != nil {
+		h.sendError(message.Sender, "Invalid room settings")
+		return
+	}
+	if err := settings.Normalize(); err != nil {
+		h.sendError(message.Sender, err.Error())
+		return
+	}
+	if err := resolveGhost(h.store, &settings, message.Sender); err != nil {
+		h.sendError(message.Sender, err.Error())
+		return
+	}
+	h.settings = settings
+	h.syncTeams()
+	h.publishInfo()
+
+	logger.Logger.Info("[Room] Settings updated",
+		"roomId", h.roomId,
+		"settings", settings,
+	)
+	h.broadcastAll(h.settingsMessage())
+}
+
+// settingsMessage returns the room_settings message for the current settings
+func (h *Hub) settingsMessage() Message {
+	content, err := encodeContent(h.settings)
+	if err != nil {
+		logger.Logger.Error("[Room] Failed to encode settings", "error", err)
+	}
+	return Message{
+		Type:      RoomSettingsMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	}
+}
+
+// transferHostIfGone hands the host role to the longest connected player
+// when the host has no player connection left in the room. It runs once
+// the session of the host expired, a host who lost the socket keeps the
+// role while they can resume. A room without players keeps its host, they
+// may come back.
+func (h *Hub) transferHostIfGone() {
+	var next *Clients
+	for client := range h.clients {
+		if client.spectator {
+			continue
+		}
+		if client.id == h.hostID {
+			return
+		}
+		if next == nil || client.joinedAt.Before(next.joinedAt) {
+			next = client
+		}
+	}
+	if next == nil {
+		return
+	}
+
+	previous := h.hostID
+	h.hostID = next.id
+	logger.Logger.Info("[Room] Host transferred",
+		"roomId", h.roomId,
+		"from", previous,
+		"to", next.id,
+	)
+
+	content, err := encodeContent(HostPayload{HostID: next.id, HostName: next.name})
+	if err != nil {
+		logger.Logger.Error("[Room] Failed to encode host info", "error", err)
+		return
+	}
+	h.broadcastAll(Message{
+		Type:      HostChanged,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
+
+// anyoneInGame tells if a connected player is still racing
+func (h *Hub) anyoneInGame() bool {
+	for client := range h.clients {
+		if client.status == StatusInGame {
+			return true
+		}
+	}
+	return false
+}
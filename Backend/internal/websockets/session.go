@@ -0,0 +1,231 @@
+// This file implements resumable client sessions. Every connection gets a
+// session token and every message the hub sends to it carries a per-client
+// sequence number. Sent messages are kept in a bounded replay buffer, so a
+// client that lost its socket can reconnect with ?resume=<token>&last_seq=N
+// and receive exactly what it missed, keeping its status and race progress.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+const (
+	replayBufferSize    = 256             // messages kept per session for replay
+	sessionResumeWindow = 2 * time.Minute // how long a disconnected session can be resumed
+)
+
+// SessionPayload is the content of a session message
+type SessionPayload struct {
+	Token    string `json:"token"`    // pass as ?resume= when reconnecting
+	LastSeq  uint64 `json:"last_seq"` // sequence number of the last message sent
+	Resumed  bool   `json:"resumed"`  // true when an existing session was resumed
+	Replayed int    `json:"replayed"` // number of missed messages that follow
+	Complete bool   `json:"complete"` // false when missed messages fell out of the buffer
+	Protocol int    `json:"protocol"` // protocol version negotiated for the connection
+}
+
+// bufferedMessage is kept as a Message, a resuming client may speak
+// another protocol version than the connection it was sent to
+type bufferedMessage struct {
+	seq uint64
+	msg Message
+}
+
+// clientSession outlives the websocket connection it was created for
+type clientSession struct {
+	token      string
+	userID     string
+	status     string            // player status, restored on resume
+	seq        uint64            // last sequence number assigned
+	buffer     []bufferedMessage // most recent messages, oldest first
+	client     *Clients          // attached connection, nil while disconnected
+	detachedAt time.Time
+}
+
+// push assigns the next sequence number to msg and keeps it in the replay
+// buffer
+func (s *clientSession) push(msg Message) Message {
+	s.seq++
+	msg.Seq = s.seq
+
+	if len(s.buffer) == replayBufferSize {
+		copy(s.buffer, s.buffer[1:])
+		s.buffer = s.buffer[:len(s.buffer)-1]
+	}
+	s.buffer = append(s.buffer, bufferedMessage{seq: s.seq, msg: msg})
+	return msg
+}
+
+// since returns the buffered messages after lastSeq. complete is false
+// when some of them were already evicted from the buffer.
+func (s *clientSession) since(lastSeq uint64) (missed []bufferedMessage, complete bool) {
+	if lastSeq >= s.seq {
+		return nil, true
+	}
+	complete = len(s.buffer) > 0 && s.buffer[0].seq <= lastSeq+1
+	for _, m := range s.buffer {
+		if m.seq > lastSeq {
+			missed = append(missed, m)
+		}
+	}
+	return missed, complete
+}
+
+// attachSession binds a freshly registered client to a session. When the
+// client asked to resume a session of the same user it gets that session
+// back together with its status and the messages it missed, otherwise a
+// new session is created.
+func (h *Hub) attachSession(c *Clients) {
+	h.pruneSessions(time.Now())
+
+	s, ok := h.sessions[c.resumeToken]
+	if !ok || c.resumeToken == "" || s.userID != c.id {
+		s = &clientSession{
+			token:  GenerateRoomId(),
+			userID: c.id,
+			status: c.status,
+		}
+		h.sessions[s.token] = s
+		s.client = c
+		c.session = s
+		h.sendSessionInfo(c, SessionPayload{Token: s.token, LastSeq: s.seq, Complete: true, Protocol: c.protocol})
+		return
+	}
+
+	// The old connection may not have been unregistered yet
+	if old := s.client; old != nil && old != c {
+		if _, registered := h.clients[old]; registered {
+			delete(h.clients, old)
+			close(old.send)
+		}
+	}
+	s.client = c
+	c.session = s
+	c.status = s.status
+
+	missed, complete := s.since(c.lastSeq)
+	h.sendSessionInfo(c, SessionPayload{
+		Token:    s.token,
+		LastSeq:  s.seq,
+		Resumed:  true,
+		Replayed: len(missed),
+		Complete: complete,
+		Protocol: c.protocol,
+	})
+	for _, m := range missed {
+		c.send <- c.encode(m.msg)
+	}
+
+	logger.Logger.Info("[Hub] Session resumed",
+		"roomId", h.roomId,
+		"client", c.id,
+		"last_seq", c.lastSeq,
+		"replayed", len(missed),
+		"complete", complete,
+	)
+}
+
+// detachSession keeps the session of a leaving client around so it can be
+// resumed within sessionResumeWindow
+func (h *Hub) detachSession(c *Clients) {
+	s := c.session
+	if s == nil || s.client != c {
+		return
+	}
+	s.status = c.status
+	s.client = nil
+	s.detachedAt = time.Now()
+
+	// Nothing else may prune the session while the room waits on the race
+	// or on its host
+	if h.race != nil || c.id == h.hostID {
+		time.AfterFunc(sessionResumeWindow+time.Second, func() {
+			select {
+			case h.sessionExpiries <- struct{}{}:
+			default:
+			}
+		})
+	}
+}
+
+// pruneSessions drops sessions that have been disconnected for too long.
+// Their players can't come back, the race stops waiting for them and a
+// host who is gone for good hands the role over.
+func (h *Hub) pruneSessions(now time.Time) {
+	hostGone := false
+	for token, s := range h.sessions {
+		if s.client == nil && now.Sub(s.detachedAt) > sessionResumeWindow {
+			delete(h.sessions, token)
+			h.abandonRace(s.userID)
+			hostGone = hostGone || s.userID == h.hostID
+		}
+	}
+	if hostGone {
+		h.transferHostIfGone()
+		h.BroadcastPlayerList()
+	}
+}
+
+// sendSessionInfo tells a client its session token. It is connection
+// metadata, so it is neither sequenced nor buffered.
+func (h *Hub) sendSessionInfo(c *Clients, info SessionPayload) {
+	content, err := encodeContent(info)
+	if err != nil {
+		logger.Logger.Error("[Hub] Failed to encode session info", "error", err)
+		return
+	}
+	c.send <- c.encode(Message{
+		Type:      SessionMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
+
+// deliver sends msg to a session. Disconnected sessions still get the
+// message sequenced and buffered so it can be replayed on resume.
+func (h *Hub) deliver(s *clientSession, msg Message) {
+	msg = s.push(msg)
+	if s.client != nil {
+		s.client.send <- s.client.encode(msg)
+	}
+}
+
+// sendTo sends a message to a single client
+func (h *Hub) sendTo(c *Clients, msg Message) {
+	if c.session == nil {
+		c.send <- c.encode(msg)
+		return
+	}
+	h.deliver(c.session, msg)
+}
+
+// broadcastAll sends a message to every session of the room
+func (h *Hub) broadcastAll(msg Message) {
+	for _, s := range h.sessions {
+		h.deliver(s, msg)
+	}
+}
+
+// broadcastExcept sends a message to every session not owned by userID
+func (h *Hub) broadcastExcept(msg Message, userID string) {
+	for _, s := range h.sessions {
+		if s.userID == userID {
+			continue
+		}
+		h.deliver(s, msg)
+	}
+}
+
+// sendToUser sends a message to every session owned by userID
+func (h *Hub) sendToUser(userID string, msg Message) {
+	for _, s := range h.sessions {
+		if s.userID == userID {
+			h.deliver(s, msg)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package websockets
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+func TestSessionSince(t *testing.T) {
+	tests := []struct {
+		name     string
+		sent     int
+		lastSeq  uint64
+		missed   int
+		first    uint64 // sequence number of the first missed message
+		complete bool
+	}{
+		{"nothing sent", 0, 0, 0, 0, true},
+		{"up to date", 5, 5, 0, 0, true},
+		{"ahead of the server", 5, 7, 0, 0, true},
+		{"missed everything", 5, 0, 5, 1, true},
+		{"missed the last one", 5, 4, 1, 5, true},
+		{"buffer just full", replayBufferSize, 0, replayBufferSize, 1, true},
+		{"first one evicted", replayBufferSize + 1, 0, replayBufferSize, 2, false},
+		{"evicted one already seen", replayBufferSize + 1, 1, replayBufferSize, 2, true},
+		{"missed before the buffer", 300, 300 - replayBufferSize - 1, replayBufferSize, 300 - replayBufferSize + 1, false},
+		{"missed from the buffer start", 300, 300 - replayBufferSize, replayBufferSize, 300 - replayBufferSize + 1, true},
+		{"missed the last of a full buffer", 300, 299, 1, 300, true},
+	}
+	for _, tt := range tests {
+		s := &clientSession{}
+		for range tt.sent {
+			s.push(Message{Type: BroadcastMessage})
+		}
+		if len(s.buffer) > replayBufferSize {
+			t.Fatalf("%s: %d messages buffered, more than %d", tt.name, len(s.buffer), replayBufferSize)
+		}
+
+		missed, complete := s.since(tt.lastSeq)
+		if len(missed) != tt.missed || complete != tt.complete {
+			t.Errorf("%s: %d missed, complete %v, want %d and %v", tt.name, len(missed), complete, tt.missed, tt.complete)
+			continue
+		}
+		if len(missed) > 0 && (missed[0].seq != tt.first || missed[len(missed)-1].seq != uint64(tt.sent)) {
+			t.Errorf("%s: missed %d to %d, want %d to %d", tt.name, missed[0].seq, missed[len(missed)-1].seq, tt.first, tt.sent)
+		}
+		for i, m := range missed {
+			if m.msg.Seq != m.seq || i > 0 && m.seq != missed[i-1].seq+1 {
+				t.Errorf("%s: missed message %d has seq %d", tt.name, i, m.msg.Seq)
+				break
+			}
+		}
+	}
+}
+
+func TestSessionResume(t *testing.T) {
+	logger.InitLogger("")
+	h := NewHub(DefaultRoomSettings(), "u")
+	newClient := func() *Clients {
+		return &Clients{id: "u", send: make(chan []byte, 16), protocol: LatestProtocol, encoding: EncodingFor("")}
+	}
+
+	first := newClient()
+	h.attachSession(first)
+	for range 3 {
+		h.broadcastAll(Message{Type: BroadcastMessage, Content: json.RawMessage(`"hi"`)})
+	}
+	h.detachSession(first)
+
+	second := newClient()
+	second.resumeToken, second.lastSeq = first.session.token, 1
+	h.attachSession(second)
+	if second.session != first.session {
+		t.Fatal("session not resumed")
+	}
+	var info SessionPayload
+	var msg Message
+	if err := json.Unmarshal(<-second.send, &msg); err != nil || json.Unmarshal(msg.Content, &info) != nil {
+		t.Fatalf("no session info: %v", err)
+	}
+	if !info.Resumed || info.Replayed != 2 || !info.Complete || info.LastSeq != 3 {
+		t.Errorf("session info %+v, want 2 of 3 replayed", info)
+	}
+	for seq := uint64(2); seq <= 3; seq++ {
+		if err := json.Unmarshal(<-second.send, &msg); err != nil || msg.Seq != seq {
+			t.Errorf("replayed seq %d, want %d (%v)", msg.Seq, seq, err)
+		}
+	}
+
+	// Another user can't take the session over
+	other := newClient()
+	other.id, other.resumeToken = "v", first.session.token
+	h.attachSession(other)
+	if other.session == first.session {
+		t.Error("session resumed by another user")
+	}
+}
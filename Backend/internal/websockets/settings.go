@@ -0,0 +1,275 @@
+package websockets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/corpus"
+	"github.com/ManogyaDahal/GoType/internal/text"
+)
+
+// RaceMode selects how a round is played
+type RaceMode string
+
+const (
+	ModeWords RaceMode = "words" // type a fixed number of words until done
+	ModeTimed RaceMode = "timed" // type as much as possible before the time is up
+
+	// Rounds back to back, the last placed player is eliminated each round
+	ModeElimination RaceMode = "elimination"
+
+	ModeQuote  RaceMode = "quote"  // a quote or passage from the corpus
+	ModeCustom RaceMode = "custom" // a text submitted by the host
+	ModeCode   RaceMode = "code"   // a source code snippet, over several lines
+)
+
+// Function tells if the race mode is valid
+func IsValidMode(m RaceMode) bool {
+	switch m {
+	case ModeWords, ModeTimed, ModeElimination, ModeQuote, ModeCustom, ModeCode:
+		return true
+	default:
+		return false
+	}
+}
+
+// Indentation is how the indentation of code snippets is handled
+type Indentation string
+
+const (
+	IndentAuto  Indentation = "auto"  // filled in, players only type the code
+	IndentTyped Indentation = "typed" // leading whitespace has to be typed
+)
+
+// Privacy of a room
+type Privacy string
+
+const (
+	PrivacyPublic  Privacy = "public"  // listed in the room browser
+	PrivacyPrivate Privacy = "private" // joinable by room id only
+)
+
+// Limits for room settings
+const (
+	DefaultMaxPlayers = 8
+	MinMaxPlayers     = 2
+	MaxMaxPlayers     = 32
+
+	DefaultMaxSpectators = 16 // 0 disables spectating
+	MaxMaxSpectators     = 64
+
+	DefaultTimeLimit = 180 // seconds a round may last, unfinished players DNF
+	MinTimeLimit     = 30
+	MaxTimeLimit     = 900
+
+	DefaultCountdown = 3 // seconds between game_go and the start
+	MinCountdown     = 1
+	MaxCountdown     = 10
+
+	DefaultDuration = 30 // seconds of a timed round
+
+	MinTeams = 2 // 0 disables teams
+	MaxTeams = 4
+)
+
+// TimedDurations are the durations a timed round can last, in seconds
+var TimedDurations = []int{15, 30, 60, 120}
+
+// IsValidDuration tells if a timed round can last seconds
+func IsValidDuration(seconds int) bool {
+	for _, d := range TimedDurations {
+		if d == seconds {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomSettings is the configuration of a room, chosen by its host
+type RoomSettings struct {
+	MaxPlayers    int      `json:"max_players"`
+	MaxSpectators int      `json:"max_spectators"` // 0 disables spectating
+	WordCount     int      `json:"word_count"`
+	Mode          RaceMode `json:"mode"`
+	Language      string   `json:"language"`
+	Layout        string   `json:"layout"` // keyboard layout, one of the language's
+	Privacy       Privacy  `json:"privacy"`
+	Countdown     int      `json:"countdown"`  // seconds
+	TimeLimit     int      `json:"time_limit"` // seconds
+	Duration      int      `json:"duration"`   // seconds of a timed round
+
+	Teams       int         `json:"teams"`        // number of teams, 0 for everyone on their own
+	TeamScoring TeamScoring `json:"team_scoring"` // how team scores are computed
+
+	QuoteLength corpus.Length `json:"quote_length"`          // quote mode
+	CustomText  string        `json:"custom_text,omitempty"` // custom mode
+
+	CodeLanguage string      `json:"code_language"` // code mode, corpus.CodeAny for any
+	Indentation  Indentation `json:"indentation"`   // code mode
+
+	// Ghost races every round against a recorded player, nil for none
+	Ghost *GhostSettings `json:"ghost,omitempty"`
+}
+
+// GhostSettings selects the recorded player raced against. With only
+// user_id set, the best race of that user is used ("me" is the user
+// creating the room); with race_id set, the track of user_id in that race,
+// or of its winner. See resolveGhost.
+type GhostSettings struct {
+	UserID string `json:"user_id,omitempty"`
+	RaceID string `json:"race_id,omitempty"`
+	Name   string `json:"name,omitempty"` // filled in by the server
+}
+
+// DefaultRoomSettings returns the settings used when the creator sends none
+func DefaultRoomSettings() RoomSettings {
+	return RoomSettings{
+		MaxPlayers:    DefaultMaxPlayers,
+		MaxSpectators: DefaultMaxSpectators,
+		WordCount:     text.DefaultWordCount,
+		Mode:          ModeWords,
+		Language:      text.DefaultLanguage,
+		Privacy:       PrivacyPublic,
+		Countdown:     DefaultCountdown,
+		TimeLimit:     DefaultTimeLimit,
+		Duration:      DefaultDuration,
+		TeamScoring:   TeamScoringAverage,
+		QuoteLength:   corpus.LengthAny,
+		CodeLanguage:  corpus.CodeAny,
+		Indentation:   IndentAuto,
+	}
+}
+
+// Normalize fills unset fields with defaults and validates the rest
+func (s *RoomSettings) Normalize() error {
+	d := DefaultRoomSettings()
+
+	if s.MaxPlayers == 0 {
+		s.MaxPlayers = d.MaxPlayers
+	}
+	if s.MaxPlayers < MinMaxPlayers || s.MaxPlayers > MaxMaxPlayers {
+		return fmt.Errorf("max_players must be between %d and %d", MinMaxPlayers, MaxMaxPlayers)
+	}
+
+	// Zero is meaningful here (no spectators), the default comes from
+	// DefaultRoomSettings when the room is created
+	if s.MaxSpectators < 0 || s.MaxSpectators > MaxMaxSpectators {
+		return fmt.Errorf("max_spectators must be between 0 and %d", MaxMaxSpectators)
+	}
+
+	if s.WordCount == 0 {
+		s.WordCount = d.WordCount
+	}
+	if s.WordCount < text.MinWordCount || s.WordCount > text.MaxWordCount {
+		return fmt.Errorf("word_count must be between %d and %d", text.MinWordCount, text.MaxWordCount)
+	}
+
+	if s.Mode == "" {
+		s.Mode = d.Mode
+	}
+	if !IsValidMode(s.Mode) {
+		return fmt.Errorf("invalid mode: %s", s.Mode)
+	}
+
+	if s.Language == "" {
+		s.Language = d.Language
+	}
+	if !text.IsSupportedLanguage(s.Language) {
+		return fmt.Errorf("unsupported language: %s", s.Language)
+	}
+	lang, _ := text.Lookup(s.Language)
+	if s.Layout == "" {
+		s.Layout = lang.DefaultLayout()
+	}
+	if !lang.HasLayout(s.Layout) {
+		return fmt.Errorf("unsupported layout for %s: %s", lang.Name, s.Layout)
+	}
+
+	if s.Privacy == "" {
+		s.Privacy = d.Privacy
+	}
+	if s.Privacy != PrivacyPublic && s.Privacy != PrivacyPrivate {
+		return fmt.Errorf("invalid privacy: %s", s.Privacy)
+	}
+
+	if s.Countdown == 0 {
+		s.Countdown = d.Countdown
+	}
+	if s.Countdown < MinCountdown || s.Countdown > MaxCountdown {
+		return fmt.Errorf("countdown must be between %d and %d seconds", MinCountdown, MaxCountdown)
+	}
+
+	if s.TimeLimit == 0 {
+		s.TimeLimit = d.TimeLimit
+	}
+	if s.TimeLimit < MinTimeLimit || s.TimeLimit > MaxTimeLimit {
+		return fmt.Errorf("time_limit must be between %d and %d seconds", MinTimeLimit, MaxTimeLimit)
+	}
+
+	if s.Duration == 0 {
+		s.Duration = d.Duration
+	}
+	if !IsValidDuration(s.Duration) {
+		return fmt.Errorf("duration must be one of %v seconds", TimedDurations)
+	}
+
+	if s.QuoteLength == "" {
+		s.QuoteLength = d.QuoteLength
+	}
+	if !corpus.IsValidLength(s.QuoteLength) {
+		return fmt.Errorf("invalid quote_length: %s", s.QuoteLength)
+	}
+	if s.Mode == ModeQuote && !corpus.Default.Has(s.Language, s.QuoteLength) {
+		return fmt.Errorf("no %s quotes available in %s", s.QuoteLength, s.Language)
+	}
+	if s.Mode == ModeCustom {
+		cleaned, err := corpus.CleanCustom(s.CustomText)
+		if err != nil {
+			return err
+		}
+		if !lang.MatchesScript(cleaned) {
+			return fmt.Errorf("custom text is not written in the %s script", lang.Name)
+		}
+		s.CustomText = cleaned
+	}
+
+	if s.CodeLanguage == "" {
+		s.CodeLanguage = d.CodeLanguage
+	}
+	if !corpus.DefaultCode.Has(s.CodeLanguage) {
+		return fmt.Errorf("no code snippets available in %s", s.CodeLanguage)
+	}
+	if s.Indentation == "" {
+		s.Indentation = d.Indentation
+	}
+	if s.Indentation != IndentAuto && s.Indentation != IndentTyped {
+		return fmt.Errorf("invalid indentation: %s", s.Indentation)
+	}
+
+	if s.Teams != 0 && (s.Teams < MinTeams || s.Teams > MaxTeams) {
+		return fmt.Errorf("teams must be 0 or between %d and %d", MinTeams, MaxTeams)
+	}
+	if s.Teams > s.MaxPlayers {
+		return fmt.Errorf("teams can't exceed max_players")
+	}
+	if s.TeamScoring == "" {
+		s.TeamScoring = d.TeamScoring
+	}
+	if s.TeamScoring != TeamScoringAverage && s.TeamScoring != TeamScoringSum {
+		return fmt.Errorf("invalid team_scoring: %s", s.TeamScoring)
+	}
+
+	if s.Ghost != nil && s.Ghost.UserID == "" && s.Ghost.RaceID == "" {
+		return fmt.Errorf("ghost needs a user_id or a race_id")
+	}
+	return nil
+}
+
+// roundLimit is how long a round may last: the duration of a timed round,
+// the time limit otherwise
+func (s RoomSettings) roundLimit() time.Duration {
+	if s.Mode == ModeTimed {
+		return time.Duration(s.Duration) * time.Second
+	}
+	return time.Duration(s.TimeLimit) * time.Second
+}
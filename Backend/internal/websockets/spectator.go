@@ -0,0 +1,75 @@
+// This file implements spectators. A spectator connects with
+// action=spectate, receives everything the room receives (progress,
+// game_go, results) but never takes part: it has no ready state, is not
+// waited for before a round starts and is not enrolled in the race.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// SpectatorInfo is an entry of the spectator_list message
+type SpectatorInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// playerCount returns the number of player connections (spectators excluded)
+func (h *Hub) playerCount() int {
+	n := 0
+	for client := range h.clients {
+		if !client.spectator {
+			n++
+		}
+	}
+	return n
+}
+
+// spectatorCount returns the number of spectator connections
+func (h *Hub) spectatorCount() int {
+	return len(h.clients) - h.playerCount()
+}
+
+// isSpectator tells if every connection of the user is a spectator.
+// Messages are routed by user ID, so a user with at least one player
+// connection is treated as a player.
+func (h *Hub) isSpectator(userID string) bool {
+	found := false
+	for client := range h.clients {
+		if client.id != userID {
+			continue
+		}
+		if !client.spectator {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// broadcastSpectatorList sends the spectators of the room to everyone.
+// The content is a JSON encoded string, same as the player list.
+func (h *Hub) broadcastSpectatorList() {
+	spectators := make(SpectatorListPayload, 0)
+	for client := range h.clients {
+		if client.spectator {
+			spectators = append(spectators, SpectatorInfo{ID: client.id, Name: client.name})
+		}
+	}
+
+	content, err := encodeContent(spectators)
+	if err != nil {
+		logger.Logger.Warn("[Hub] Failed to encode spectator list", "error", err)
+		return
+	}
+	h.broadcastAll(Message{
+		Type:      SpectatorListMessage,
+		RoomId:    h.roomId,
+		Sender:    "server",
+		Content:   content,
+		TimeStamp: time.Now(),
+	})
+}
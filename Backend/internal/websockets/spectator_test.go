@@ -0,0 +1,117 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/race"
+)
+
+// A spectator follows the round without taking part in it: nobody waits
+// for it, it isn't raced or ranked, but it sees the progress and results
+func TestSpectatorsWatchOnly(t *testing.T) {
+	h := newTestHub(t, DefaultRoomSettings(), "host", "guest")
+	watcher := newTestClient("watcher")
+	watcher.spectator = true
+	connect(h, watcher)
+
+	h.handleStartRequest(Message{Type: GameStart, Sender: "host"})
+	h.PlayerJoinedGame("watcher")
+	h.PlayerJoinedGame("host")
+	if h.state != RoomStateLobby {
+		t.Fatal("round started without the guest")
+	}
+	h.PlayerJoinedGame("guest")
+	h.stopRaceTimer()
+	if h.race == nil || h.expectedPlayers != 2 {
+		t.Fatalf("round not started with the 2 players, %d expected", h.expectedPlayers)
+	}
+	if h.race.Player("watcher") != nil {
+		t.Error("spectator enrolled in the race")
+	}
+
+	// Restarted in the past, the round's race waits for the countdown
+	startTestRace(t, h, "go", time.Now().Add(-time.Second))
+	received(t, watcher, "")
+	submit(h, "host", race.Input{Keys: "go"})
+	submit(h, "watcher", race.Input{Keys: "go"})
+	if len(received(t, watcher, PlayerProgress)) != 1 {
+		t.Error("spectator didn't see the progress of the host")
+	}
+	submit(h, "guest", race.Input{Keys: "g"})
+	h.handleRaceTimeout(h.round)
+
+	msgs := received(t, watcher, RaceResultsMessage)
+	if len(msgs) != 1 {
+		t.Fatalf("spectator sent %d race_results, want 1", len(msgs))
+	}
+	var results ResultsPayload
+	if err := msgs[0].decodeContent(&results); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results.Results {
+		if r.ID == "watcher" {
+			t.Error("spectator ranked in the results")
+		}
+	}
+	if len(results.Results) != 2 {
+		t.Errorf("%d results, want the 2 players", len(results.Results))
+	}
+
+	// The player list goes through the Run loop, the spectator list straight
+	// to the members
+	for len(h.broadcast) > 0 {
+		<-h.broadcast
+	}
+	h.BroadcastPlayerList()
+	var players PlayerListPayload
+	if err := (<-h.broadcast).decodeContent(&players); err != nil {
+		t.Fatal(err)
+	}
+	var spectators SpectatorListPayload
+	if msgs := received(t, h.clientByID("host"), SpectatorListMessage); len(msgs) > 0 {
+		msgs[len(msgs)-1].decodeContent(&spectators)
+	}
+	if len(players) != 2 || len(spectators) != 1 || spectators[0].ID != "watcher" {
+		t.Errorf("players %v and spectators %v, want 2 players and the watcher", players, spectators)
+	}
+	if info := h.Info(); info.PlayerCount != 2 || info.SpectatorCount != 1 {
+		t.Errorf("room info counts %d players, %d spectators", info.PlayerCount, info.SpectatorCount)
+	}
+}
+
+func TestSpectatorSeats(t *testing.T) {
+	spectator := func(id string) *Clients {
+		c := newTestClient(id)
+		c.spectator = true
+		return c
+	}
+	tests := []struct {
+		name       string
+		players    []string
+		seats      int
+		spectators []string
+		joining    *Clients
+		want       bool
+	}{
+		{"spectating disabled", []string{"host"}, 0, nil, spectator("w"), false},
+		{"free seat", []string{"host"}, 1, nil, spectator("w"), true},
+		{"seats taken", []string{"host"}, 1, []string{"v"}, spectator("w"), false},
+		{"second connection", []string{"host"}, 1, []string{"w"}, spectator("w"), true},
+		{"spectators don't take player seats", []string{"host"}, 2, []string{"v", "w"}, newTestClient("guest"), true},
+		{"room full of players", []string{"host", "guest"}, 2, nil, newTestClient("third"), false},
+		{"full room still watchable", []string{"host", "guest"}, 2, nil, spectator("w"), true},
+	}
+	for _, tt := range tests {
+		settings := DefaultRoomSettings()
+		settings.MaxPlayers = 2
+		settings.MaxSpectators = tt.seats
+		h := newTestHub(t, settings, tt.players...)
+		for _, id := range tt.spectators {
+			connect(h, spectator(id))
+		}
+		if got := h.canJoin(tt.joining); got != tt.want {
+			t.Errorf("%s: canJoin = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
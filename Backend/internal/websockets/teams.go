@@ -0,0 +1,241 @@
+// This file implements team races. When the room settings enable teams,
+// every player belongs to a team: new players join the smallest team, the
+// host can move players with team_assign or have the server balance the
+// teams by skill rating with team_balance. A team's score is the summed or
+// average WPM of its members, race_results then carries the team standings
+// next to the individual ones.
+
+package websockets
+
+import (
+	"sort"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/rating"
+	"github.com/ManogyaDahal/GoType/internal/round"
+)
+
+// TeamScoring is how the WPM of the members make up a team's score
+type TeamScoring string
+
+const (
+	TeamScoringAverage TeamScoring = "average" // average WPM of the members
+	TeamScoringSum     TeamScoring = "sum"     // summed WPM of the members
+)
+
+// TeamResult is the standing of one team in race_results
+type TeamResult struct {
+	Team    int      `json:"team"`
+	Place   int      `json:"place"`
+	Score   float64  `json:"score"`
+	Members []string `json:"members"` // user IDs of the members that raced
+}
+
+// TeamAssignPayload is the content of a team_assign message
+type TeamAssignPayload struct {
+	PlayerID string `json:"player_id"`
+	Team     int    `json:"team"`
+}
+
+// teamOf returns the team of a player, 0 when teams are off
+func (h *Hub) teamOf(userID string) int {
+	if h.settings.Teams == 0 {
+		return 0
+	}
+	return h.teams[userID]
+}
+
+// teamSizes counts the connected players of every team, index 0 unused
+func (h *Hub) teamSizes() []int {
+	sizes := make([]int, h.settings.Teams+1)
+	seen := make(map[string]bool)
+	for client := range h.clients {
+		if client.spectator || seen[client.id] {
+			continue
+		}
+		seen[client.id] = true
+		if t := h.teams[client.id]; t > 0 && t <= h.settings.Teams {
+			sizes[t]++
+		}
+	}
+	return sizes
+}
+
+// syncTeams puts every player without a valid team into the smallest one.
+// Called when a player joins and when the number of teams changes.
+func (h *Hub) syncTeams() {
+	if h.settings.Teams == 0 {
+		h.teams = make(map[string]int)
+		return
+	}
+	for client := range h.clients {
+		if client.spectator {
+			continue
+		}
+		if t := h.teams[client.id]; t > 0 && t <= h.settings.Teams {
+			continue
+		}
+		sizes := h.teamSizes()
+		smallest := 1
+		for t := 2; t <= h.settings.Teams; t++ {
+			if sizes[t] < sizes[smallest] {
+				smallest = t
+			}
+		}
+		h.teams[client.id] = smallest
+	}
+}
+
+// handleTeamAssign lets the host move a player to another team
+func (h *Hub) handleTeamAssign(message Message) {
+	if !h.isHost(message.Sender) {
+		h.sendError(message.Sender, "Only the host can assign teams")
+		return
+	}
+	if h.settings.Teams == 0 {
+		h.sendError(message.Sender, "Teams are disabled in this room")
+		return
+	}
+	if h.state == RoomStateRacing {
+		h.sendError(message.Sender, "Teams can't be changed during a round")
+		return
+	}
+
+	var a TeamAssignPayload
+	if err := message.decodeContent(&a); err != nil {
+		h.sendError(message.Sender, "Invalid team assignment")
+		return
+	}
+	if a.Team < 1 || a.Team > h.settings.Teams {
+		h.sendError(message.Sender, "No such team")
+		return
+	}
+	if h.clientByID(a.PlayerID) == nil || h.isSpectator(a.PlayerID) {
+		h.sendError(message.Sender, "No such player")
+		return
+	}
+
+	h.teams[a.PlayerID] = a.Team
+	logger.Logger.Info("[Teams] Player assigned",
+		"roomId", h.roomId,
+		"player", a.PlayerID,
+		"team", a.Team,
+	)
+	h.BroadcastPlayerList()
+}
+
+// handleTeamBalance lets the host have the server balance the teams
+func (h *Hub) handleTeamBalance(message Message) {
+	if !h.isHost(message.Sender) {
+		h.sendError(message.Sender, "Only the host can balance teams")
+		return
+	}
+	if h.settings.Teams == 0 {
+		h.sendError(message.Sender, "Teams are disabled in this room")
+		return
+	}
+	if h.state == RoomStateRacing {
+		h.sendError(message.Sender, "Teams can't be changed during a round")
+		return
+	}
+	h.balanceTeams()
+	h.BroadcastPlayerList()
+}
+
+// balanceTeams splits the players into teams of similar strength: players
+// are taken from the highest rated down and each goes to the team with
+// the lowest total rating among the teams with the fewest members
+func (h *Hub) balanceTeams() {
+	type ranked struct {
+		id     string
+		rating float64
+	}
+	players := make([]ranked, 0, len(h.clients))
+	seen := make(map[string]bool)
+	for client := range h.clients {
+		if client.spectator || seen[client.id] {
+			continue
+		}
+		seen[client.id] = true
+		r := rating.Initial
+		if h.store != nil {
+			if stored, err := rating.Lookup(h.store, client.id); err == nil {
+				r = stored.Rating
+			}
+		}
+		players = append(players, ranked{id: client.id, rating: r})
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].rating != players[j].rating {
+			return players[i].rating > players[j].rating
+		}
+		return players[i].id < players[j].id
+	})
+
+	sizes := make([]int, h.settings.Teams+1)
+	totals := make([]float64, h.settings.Teams+1)
+	h.teams = make(map[string]int)
+	for _, p := range players {
+		best := 1
+		for t := 2; t <= h.settings.Teams; t++ {
+			if sizes[t] < sizes[best] || (sizes[t] == sizes[best] && totals[t] < totals[best]) {
+				best = t
+			}
+		}
+		h.teams[p.id] = best
+		sizes[best]++
+		totals[best] += p.rating
+	}
+
+	logger.Logger.Info("[Teams] Teams balanced by rating",
+		"roomId", h.roomId,
+		"teams", h.settings.Teams,
+		"players", len(players),
+	)
+}
+
+// teamResults scores the teams from the individual results, which get
+// their team filled in. Flagged results count for nothing. Teams with the
+// same score are placed in team order.
+func (h *Hub) teamResults(results []RaceResult) []TeamResult {
+	if h.settings.Teams == 0 {
+		return nil
+	}
+
+	byTeam := make(map[int]*TeamResult)
+	for i := range results {
+		r := &results[i]
+		r.Team = h.teamOf(r.ID)
+		if r.Team == 0 {
+			continue
+		}
+		tr, ok := byTeam[r.Team]
+		if !ok {
+			tr = &TeamResult{Team: r.Team}
+			byTeam[r.Team] = tr
+		}
+		tr.Members = append(tr.Members, r.ID)
+		if !r.Flagged {
+			tr.Score += r.WPM
+		}
+	}
+
+	teams := make([]TeamResult, 0, len(byTeam))
+	for _, tr := range byTeam {
+		if h.settings.TeamScoring == TeamScoringAverage {
+			tr.Score /= float64(len(tr.Members))
+		}
+		tr.Score = round.Tenth(tr.Score)
+		teams = append(teams, *tr)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if teams[i].Score != teams[j].Score {
+			return teams[i].Score > teams[j].Score
+		}
+		return teams[i].Team < teams[j].Team
+	})
+	for i := range teams {
+		teams[i].Place = i + 1
+	}
+	return teams
+}
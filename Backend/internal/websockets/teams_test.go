@@ -0,0 +1,151 @@
+package websockets
+
+import (
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/ManogyaDahal/GoType/internal/logger"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+func TestBalanceTeams(t *testing.T) {
+	logger.InitLogger("")
+	tests := []struct {
+		name    string
+		teams   int
+		ratings map[string]float64 // players, 0 for unrated
+		want    map[string]int
+	}{
+		{
+			name:    "even",
+			teams:   2,
+			ratings: map[string]float64{"a": 1800, "b": 1600, "c": 1500, "d": 1400},
+			want:    map[string]int{"a": 1, "b": 2, "c": 2, "d": 1},
+		},
+		{
+			name:    "one player more",
+			teams:   2,
+			ratings: map[string]float64{"a": 1800, "b": 1600, "c": 1500},
+			want:    map[string]int{"a": 1, "b": 2, "c": 2},
+		},
+		{
+			name:    "fewer players than teams",
+			teams:   3,
+			ratings: map[string]float64{"a": 1800, "b": 1600},
+			want:    map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:    "three teams, one of two",
+			teams:   3,
+			ratings: map[string]float64{"a": 1800, "b": 1600, "c": 1500, "d": 1400},
+			want:    map[string]int{"a": 1, "b": 2, "c": 3, "d": 3},
+		},
+		{
+			name:    "equal ratings by ID",
+			teams:   2,
+			ratings: map[string]float64{"x": 0, "y": 0, "z": 0},
+			want:    map[string]int{"x": 1, "y": 2, "z": 1},
+		},
+	}
+	for _, tt := range tests {
+		settings := DefaultRoomSettings()
+		settings.Teams = tt.teams
+		h := NewHub(settings, "host")
+		h.store = storage.NewMemoryStore()
+		for id, r := range tt.ratings {
+			h.clients[&Clients{id: id, name: id}] = true
+			if r != 0 {
+				h.store.SaveRatings([]storage.Rating{{UserID: id, Rating: r}}, nil)
+			}
+		}
+		h.clients[&Clients{id: "watcher", spectator: true}] = true
+
+		h.balanceTeams()
+		if !maps.Equal(h.teams, tt.want) {
+			t.Errorf("%s: teams %v, want %v", tt.name, h.teams, tt.want)
+		}
+	}
+}
+
+func TestTeamResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		teams   map[string]int
+		scoring TeamScoring
+		results []RaceResult
+		want    string // team:score per place
+	}{
+		{
+			name:    "average of uneven teams",
+			teams:   map[string]int{"a": 1, "b": 1, "c": 2},
+			scoring: TeamScoringAverage,
+			results: []RaceResult{{ID: "a", WPM: 60}, {ID: "c", WPM: 45}, {ID: "b", WPM: 40}},
+			want:    "[1:50 2:45]",
+		},
+		{
+			name:    "sum of uneven teams",
+			teams:   map[string]int{"a": 1, "b": 2, "c": 2},
+			scoring: TeamScoringSum,
+			results: []RaceResult{{ID: "a", WPM: 80}, {ID: "b", WPM: 45}, {ID: "c", WPM: 40}},
+			want:    "[2:85 1:80]",
+		},
+		{
+			name:    "flagged member counts for nothing",
+			teams:   map[string]int{"a": 1, "b": 1, "c": 2},
+			scoring: TeamScoringAverage,
+			results: []RaceResult{{ID: "b", WPM: 200, Flagged: true}, {ID: "a", WPM: 60}, {ID: "c", WPM: 45}},
+			want:    "[2:45 1:30]",
+		},
+		{
+			name:    "tie placed in team order",
+			teams:   map[string]int{"a": 2, "b": 1},
+			scoring: TeamScoringAverage,
+			results: []RaceResult{{ID: "a", WPM: 50}, {ID: "b", WPM: 50}},
+			want:    "[1:50 2:50]",
+		},
+		{
+			name:    "tie after rounding",
+			teams:   map[string]int{"a": 1, "b": 1, "c": 1, "d": 2},
+			scoring: TeamScoringAverage,
+			results: []RaceResult{{ID: "a", WPM: 50}, {ID: "b", WPM: 50}, {ID: "c", WPM: 50.1}, {ID: "d", WPM: 50}},
+			want:    "[1:50 2:50]",
+		},
+		{
+			name:    "rounded to a tenth",
+			teams:   map[string]int{"a": 1, "b": 1, "c": 1},
+			scoring: TeamScoringAverage,
+			results: []RaceResult{{ID: "a", WPM: 50}, {ID: "b", WPM: 50}, {ID: "c", WPM: 51}},
+			want:    "[1:50.3]",
+		},
+	}
+	for _, tt := range tests {
+		settings := DefaultRoomSettings()
+		settings.Teams = 2
+		settings.TeamScoring = tt.scoring
+		h := newTestHub(t, settings, "host")
+		h.teams = tt.teams
+
+		teams := h.teamResults(tt.results)
+		got := make([]string, len(teams))
+		for i, tr := range teams {
+			if tr.Place != i+1 {
+				t.Errorf("%s: team %d placed %d, want %d", tt.name, tr.Team, tr.Place, i+1)
+			}
+			got[i] = fmt.Sprintf("%d:%v", tr.Team, tr.Score)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: teams %v, want %s", tt.name, got, tt.want)
+		}
+		for _, r := range tt.results {
+			if r.Team != tt.teams[r.ID] {
+				t.Errorf("%s: result of %s in team %d, want %d", tt.name, r.ID, r.Team, tt.teams[r.ID])
+			}
+		}
+	}
+
+	h := newTestHub(t, DefaultRoomSettings(), "host")
+	if teams := h.teamResults([]RaceResult{{ID: "a", WPM: 50}}); teams != nil {
+		t.Errorf("team results %v without teams", teams)
+	}
+}
@@ -0,0 +1,73 @@
+// This file collects the optional keystroke telemetry of a race. Clients
+// that enabled it send their key presses in keystrokes batches while they
+// race. The hub keeps them per participant and, once the race is over,
+// hands them to the analytics recorder, except those of results flagged by
+// the anti-cheat.
+
+package websockets
+
+import (
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/logger"
+)
+
+// handleKeystrokes buffers a keystrokes batch of a participant
+func (h *Hub) handleKeystrokes(message Message) {
+	if h.race == nil || h.race.Player(message.Sender) == nil {
+		return
+	}
+
+	var batch KeystrokesPayload
+	if err := message.decodeContent(&batch); err != nil {
+		logger.Logger.Warn("[Telemetry] Invalid keystrokes payload",
+			"sender", message.Sender,
+			"roomId", h.roomId,
+			"error", err,
+		)
+		return
+	}
+	if len(batch.Events) > analytics.MaxBatchSize {
+		batch.Events = batch.Events[:analytics.MaxBatchSize]
+	}
+
+	events := h.keystrokes[message.Sender]
+	for _, ev := range batch.Events {
+		if len(events) >= analytics.MaxEventsPerRace {
+			break
+		}
+		if ev.Valid() {
+			events = append(events, ev)
+		}
+	}
+	h.keystrokes[message.Sender] = events
+}
+
+// recordKeystrokes hands the telemetry of the finished race to the
+// recorder. The telemetry of flagged results is dropped, like the profile
+// stats leave those results out. Recording happens off the Run loop.
+func (h *Hub) recordKeystrokes(results []RaceResult, at time.Time) {
+	keystrokes := h.keystrokes
+	h.keystrokes = make(map[string][]analytics.Keystroke)
+	for _, r := range results {
+		if r.Flagged {
+			delete(keystrokes, r.ID)
+		}
+	}
+	if h.telemetry == nil || len(keystrokes) == 0 {
+		return
+	}
+
+	recorder := h.telemetry
+	go func() {
+		for userID, events := range keystrokes {
+			if err := recorder.Record(userID, events, at); err != nil {
+				logger.Logger.Error("[Telemetry] Failed to record keystrokes",
+					"user", userID,
+					"error", err,
+				)
+			}
+		}
+	}()
+}
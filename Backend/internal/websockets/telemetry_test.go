@@ -0,0 +1,48 @@
+package websockets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ManogyaDahal/GoType/internal/analytics"
+	"github.com/ManogyaDahal/GoType/internal/storage"
+)
+
+// savedStats tells which users got their typing stats saved
+type savedStats struct {
+	*storage.MemoryStore
+	saved chan string
+}
+
+func (s savedStats) SaveTypingStats(t storage.TypingStats) error {
+	s.saved <- t.UserID
+	return s.MemoryStore.SaveTypingStats(t)
+}
+
+// The telemetry of a flagged result never reaches the analytics
+func TestFlaggedTelemetryDropped(t *testing.T) {
+	store := savedStats{MemoryStore: storage.NewMemoryStore(), saved: make(chan string, 2)}
+	h := newTestHub(t, DefaultRoomSettings(), "host")
+	h.telemetry = analytics.NewRecorder(store)
+	events := []analytics.Keystroke{{Key: "g", Expected: "g", T: 0, Correct: true}, {Key: "o", Expected: "o", T: 120, Correct: true}}
+	h.keystrokes["human"] = events
+	h.keystrokes["bot"] = events
+
+	h.recordKeystrokes([]RaceResult{{ID: "human"}, {ID: "bot", Flagged: true}}, time.Now())
+	if len(h.keystrokes) != 0 {
+		t.Errorf("telemetry of %d players kept for the next race", len(h.keystrokes))
+	}
+	for {
+		select {
+		case userID := <-store.saved:
+			if userID == "bot" {
+				t.Fatal("telemetry of a flagged result recorded")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if _, err := store.GetTypingStats("human"); err != nil {
+				t.Errorf("telemetry of the unflagged result not recorded: %v", err)
+			}
+			return
+		}
+	}
+}
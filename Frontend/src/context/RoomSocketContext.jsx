@@ -7,9 +7,10 @@ import {
   useCallback,
   useMemo,
 } from "react";
-import { useParams } from "react-router-dom";
+import { useLocation, useNavigate, useParams } from "react-router-dom";
 import { WS_URL } from "@/lib/config";
 import { fetchUser } from "@/lib/api";
+import { MessageType, PROTOCOL_VERSION } from "@/lib/protocol";
 
 const RoomSocketContext = createContext(null);
 
@@ -43,10 +44,24 @@ export function useOptionalRoomSocket() {
  */
 export function RoomSocketProvider({ children }) {
   const { roomId } = useParams();
+  const navigate = useNavigate();
+  const location = useLocation();
+  // Spectating is chosen when entering the room (?spectate) and kept while
+  // moving between lobby and game.
+  const [isSpectator] = useState(() =>
+    new URLSearchParams(location.search).has("spectate"),
+  );
   const wsRef = useRef(null);
   const listenersRef = useRef(new Set());
   const [connectionStatus, setConnectionStatus] = useState("connecting");
   const [wsToken, setWsToken] = useState(null);
+  const [me, setMe] = useState(null); // { id, name } of the logged in user
+  // Resumable session: token from the server's "session" message and the
+  // last sequence number received, sent back when reconnecting.
+  const sessionRef = useRef({ token: null, lastSeq: 0 });
+  const [reconnectAttempt, setReconnectAttempt] = useState(0);
+  // Set once the server closed the room — never reconnect after that.
+  const roomClosedRef = useRef(false);
 
   // Step 1: fetch the ws_token from whoamI on mount.
   // This call goes through the Vercel proxy where the session cookie is valid.
@@ -54,6 +69,7 @@ export function RoomSocketProvider({ children }) {
     fetchUser().then((user) => {
       if (user?.ws_token) {
         setWsToken(user.ws_token);
+        setMe({ id: user.id, name: user.name });
       } else {
         // Not logged in or token missing — mark as error so the UI can react.
         setConnectionStatus("error");
@@ -78,7 +94,7 @@ export function RoomSocketProvider({ children }) {
 
   // Step 2: open the WebSocket once both roomId and wsToken are available.
   useEffect(() => {
-    if (!roomId || !wsToken) return;
+    if (!roomId || !wsToken || roomClosedRef.current) return;
 
     // If there's already an open connection for this room, don't re-create.
     if (
@@ -93,10 +109,16 @@ export function RoomSocketProvider({ children }) {
 
     // Connect directly to Render with the HMAC token — Vercel cannot proxy
     // WebSocket connections, so WS_URL must point to the Render backend.
+    const { token: resumeToken, lastSeq } = sessionRef.current;
+    const resume = resumeToken
+      ? `&resume=${encodeURIComponent(resumeToken)}&last_seq=${lastSeq}`
+      : "";
+    const action = isSpectator ? "spectate" : "join";
     const socket = new WebSocket(
-      `${WS_URL}/ws?action=join&room_id=${roomId}&token=${encodeURIComponent(wsToken)}`,
+      `${WS_URL}/ws?action=${action}&room_id=${roomId}&protocol=${PROTOCOL_VERSION}&token=${encodeURIComponent(wsToken)}${resume}`,
     );
     wsRef.current = socket;
+    let closedByUs = false;
 
     socket.onopen = () => {
       console.log("[RoomSocket] Connected to room:", roomId);
@@ -110,6 +132,32 @@ export function RoomSocketProvider({ children }) {
       } catch {
         return;
       }
+      if (data.seq) {
+        sessionRef.current.lastSeq = Math.max(
+          sessionRef.current.lastSeq,
+          data.seq,
+        );
+      }
+      if (data.type === MessageType.Session) {
+        try {
+          const info = data.content;
+          sessionRef.current.token = info.token;
+          if (!info.resumed) sessionRef.current.lastSeq = info.last_seq;
+        } catch {
+          /* ignore */
+        }
+      }
+      if (data.type === MessageType.RoomClosed) {
+        let message = "The room was closed";
+        try {
+          message = data.content.message || message;
+        } catch {
+          /* ignore */
+        }
+        roomClosedRef.current = true;
+        alert(message);
+        navigate("/");
+      }
       // Fan out to all subscribers.
       for (const listener of listenersRef.current) {
         try {
@@ -128,9 +176,15 @@ export function RoomSocketProvider({ children }) {
     socket.onclose = (e) => {
       console.log("[RoomSocket] Closed:", e.code, e.reason);
       setConnectionStatus("disconnected");
+      if (wsRef.current === socket) wsRef.current = null;
+      // Unexpected drop — reconnect and resume the session
+      if (!closedByUs && !roomClosedRef.current && e.code !== 1000) {
+        setTimeout(() => setReconnectAttempt((n) => n + 1), 1000);
+      }
     };
 
     return () => {
+      closedByUs = true;
       console.log("[RoomSocket] Cleanup: closing WebSocket for room", roomId);
       if (
         socket.readyState === WebSocket.OPEN ||
@@ -140,17 +194,19 @@ export function RoomSocketProvider({ children }) {
       }
       wsRef.current = null;
     };
-  }, [roomId, wsToken]);
+  }, [roomId, wsToken, reconnectAttempt, navigate, isSpectator]);
 
   const value = useMemo(
     () => ({
       roomId,
+      me,
+      isSpectator,
       send,
       subscribe,
       connectionStatus,
       isConnected: connectionStatus === "connected",
     }),
-    [roomId, send, subscribe, connectionStatus],
+    [roomId, me, isSpectator, send, subscribe, connectionStatus],
   );
 
   return (
@@ -9,9 +9,96 @@ export async function fetchUser() {
     if (!res.ok) return null;
 
     const data = await res.json();
-    return data; // should be { name: "..." }
+    return data; // should be { id: "...", name: "...", ws_token: "..." }
   } catch (err) {
     console.error("Error fetching user:", err);
     return null;
   }
 }
+
+// Leaderboard: filters { mode, word_count, language, window, limit, offset }.
+// Returns { window, total, offset, entries, me } or null on failure.
+export async function fetchLeaderboard(filters = {}) {
+  const params = new URLSearchParams();
+  for (const [key, value] of Object.entries(filters)) {
+    if (value !== undefined && value !== null && value !== "") {
+      params.set(key, value);
+    }
+  }
+  try {
+    const res = await fetch(`${API_URL}/api/leaderboard?${params}`, {
+      credentials: "include", // session cookie for the "me" lookup
+    });
+    if (!res.ok) return null;
+    return await res.json();
+  } catch (err) {
+    console.error("Error fetching leaderboard:", err);
+    return null;
+  }
+}
+
+// Public profile of a user ("me" for the logged in user): avatar, join
+// date, WPM / accuracy stats and rating history. Returns null on failure.
+export async function fetchProfile(userId = "me") {
+  try {
+    const res = await fetch(
+      `${API_URL}/api/users/${encodeURIComponent(userId)}`,
+      { credentials: "include" },
+    );
+    if (!res.ok) return null;
+    return await res.json();
+  } catch (err) {
+    console.error("Error fetching profile:", err);
+    return null;
+  }
+}
+
+// Past races of the logged in user, newest first.
+// Returns { total, offset, races } or null on failure.
+export async function fetchHistory({ limit = 20, offset = 0 } = {}) {
+  try {
+    const res = await fetch(
+      `${API_URL}/api/me/history?limit=${limit}&offset=${offset}`,
+      { credentials: "include" },
+    );
+    if (!res.ok) return null;
+    return await res.json();
+  } catch (err) {
+    console.error("Error fetching history:", err);
+    return null;
+  }
+}
+
+// Typing analytics of the logged in user (per-key error rates, slowest keys
+// and bigrams, consistency). Returns null on failure.
+export async function fetchAnalytics({ top = 10 } = {}) {
+  try {
+    const res = await fetch(`${API_URL}/api/me/analytics?top=${top}`, {
+      credentials: "include",
+    });
+    if (!res.ok) return null;
+    return await res.json();
+  } catch (err) {
+    console.error("Error fetching analytics:", err);
+    return null;
+  }
+}
+
+// URL of the replay download of a race: the progress stream of every
+// participant in the compact replay format.
+export function replayUrl(raceId) {
+  return `${API_URL}/api/races/${encodeURIComponent(raceId)}/replay`;
+}
+
+// Languages rooms can race in, [{ code, name }]. Returns [] on failure.
+export async function fetchLanguages() {
+  try {
+    const res = await fetch(`${API_URL}/api/languages`);
+    if (!res.ok) return [];
+    const data = await res.json();
+    return data.languages || [];
+  } catch (err) {
+    console.error("Error fetching languages:", err);
+    return [];
+  }
+}
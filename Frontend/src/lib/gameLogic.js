@@ -296,6 +296,33 @@ export function calculateWPM(correctChars, elapsedMs) {
   return Math.round(correctChars / 5 / minutes);
 }
 
+/**
+ * Split a race text into words the way the server race engine does:
+ * whitespace separates words, and a completed word takes the player to the
+ * start of the next one. Code texts span several lines; unless autoIndent
+ * is set, the indentation of a line has to be typed and belongs to the first
+ * word of the line. Returns the words and the offset of each in the text.
+ */
+export function splitWords(text, autoIndent = true) {
+  const words = [];
+  const starts = [];
+  const re = /\S+/g;
+  let match;
+  while ((match = re.exec(text)) !== null) {
+    let start = match.index;
+    if (!autoIndent) {
+      let sep = start;
+      while (sep > 0 && /\s/.test(text[sep - 1])) sep--;
+      const newline = text.lastIndexOf("\n", start - 1);
+      if (sep === 0) start = 0;
+      else if (newline >= sep) start = newline + 1;
+    }
+    words.push(text.slice(start, match.index + match[0].length));
+    starts.push(start);
+  }
+  return { words, starts };
+}
+
 // ---------------------------------------------------------------------------
 // useGameLogic hook  —  WORD-BY-WORD approach
 // ---------------------------------------------------------------------------
@@ -307,7 +334,14 @@ export function calculateWPM(correctChars, elapsedMs) {
  *
  * Returns everything the Game component needs to render characters and cursors.
  */
-export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
+export function useGameLogic({
+  text,
+  onProgress,
+  onFinish,
+  onKeystroke,
+  forcedStartTime,
+  autoIndent = true,
+}) {
   // "idle" | "playing" | "finished"
   const [gameState, setGameState] = useState("idle");
   const [currentWordIndex, setCurrentWordIndex] = useState(0);
@@ -321,6 +355,7 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
   const intervalRef = useRef(null);
   const onProgressRef = useRef(onProgress);
   const onFinishRef = useRef(onFinish);
+  const onKeystrokeRef = useRef(onKeystroke);
 
   // Keep callback refs fresh
   useEffect(() => {
@@ -329,14 +364,34 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
   useEffect(() => {
     onFinishRef.current = onFinish;
   });
+  useEffect(() => {
+    onKeystrokeRef.current = onKeystroke;
+  });
+
+  // Reports one key press (optional telemetry): key typed, character the
+  // text expected, ms since the start and whether it matched.
+  const reportKeystroke = (key, expected, ok) => {
+    if (!onKeystrokeRef.current || !startTimeRef.current) return;
+    onKeystrokeRef.current({
+      k: key,
+      e: expected,
+      t: Math.max(0, Date.now() - startTimeRef.current),
+      ok,
+    });
+  };
 
   // Split text into words (memoish via ref to avoid re-splits)
-  const wordsRef = useRef([]);
+  const wordsRef = useRef({ words: [], starts: [] });
   useEffect(() => {
-    wordsRef.current = text ? text.split(" ") : [];
-  }, [text]);
+    wordsRef.current = splitWords(text || "", autoIndent);
+  }, [text, autoIndent]);
 
-  const words = text ? text.split(" ") : [];
+  const { words, starts: wordStarts } = splitWords(text || "", autoIndent);
+
+  // Offset in the text where the word after w starts (the text length after
+  // the last word): completing word w moves the cursor there
+  const nextStart = (starts, w) =>
+    w + 1 < starts.length ? starts[w + 1] : (text || "").length;
 
   // Full reset when the prompt text changes
   useEffect(() => {
@@ -379,44 +434,28 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
   const isFinished = gameState === "finished";
 
   // Compute the cursor position in the full text string
-  // = sum of completed word lengths + spaces + currentInput.length
-  let cursorPos = 0;
-  for (let w = 0; w < currentWordIndex; w++) {
-    cursorPos += words[w].length + 1; // word + space
-  }
-  cursorPos += currentInput.length;
-
-  // Build per-character state array for the entire text
+  // = start of the current word + currentInput.length
+  const cursorPos =
+    currentWordIndex < words.length
+      ? wordStarts[currentWordIndex] + currentInput.length
+      : (text || "").length;
+
+  // Build per-character state array for the entire text. Everything before
+  // the current word is correct (we only allow advancing correct words).
   const charStates = [];
   if (text) {
-    let idx = 0;
-    for (let w = 0; w < words.length; w++) {
-      const word = words[w];
-      for (let c = 0; c < word.length; c++) {
-        if (w < currentWordIndex) {
-          // Completed word — always correct (we only allow advancing correct words)
-          charStates[idx] = "correct";
-        } else if (w === currentWordIndex) {
-          // Current word
-          if (c < currentInput.length) {
-            charStates[idx] =
-              currentInput[c] === word[c] ? "correct" : "incorrect";
-          } else {
-            charStates[idx] = "untyped";
-          }
-        } else {
-          charStates[idx] = "untyped";
-        }
-        idx++;
-      }
-      // Space after word (except the last)
-      if (w < words.length - 1) {
-        if (w < currentWordIndex) {
-          charStates[idx] = "correct";
-        } else {
-          charStates[idx] = "untyped";
-        }
-        idx++;
+    const current = wordStarts[currentWordIndex] ?? text.length;
+    for (let i = 0; i < text.length; i++) {
+      const c = i - current;
+      if (i < current) {
+        charStates[i] = "correct";
+      } else if (c < currentInput.length && c < words[currentWordIndex].length) {
+        charStates[i] =
+          currentInput[c] === words[currentWordIndex][c]
+            ? "correct"
+            : "incorrect";
+      } else {
+        charStates[i] = "untyped";
       }
     }
   }
@@ -431,7 +470,7 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
         return;
       }
 
-      const currentWords = wordsRef.current;
+      const { words: currentWords, starts: currentStarts } = wordsRef.current;
       if (currentWords.length === 0) return;
 
       // Start timer on first keypress (only if not already started by forcedStartTime)
@@ -447,12 +486,27 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
         return;
       }
 
-      // Space key — attempt to advance to next word
-      if (e.key === " ") {
+      // Space (Enter at the end of a line of code) — attempt to advance to
+      // next word
+      if (e.key === " " || e.key === "Enter") {
         e.preventDefault();
+        const key = e.key === "Enter" ? "\n" : " ";
         setCurrentInput((prev) => {
           const targetWord = currentWords[currentWordIndex];
-          if (prev !== targetWord) {
+          const separator =
+            text[currentStarts[currentWordIndex] + targetWord.length] ?? " ";
+          const expected =
+            prev.length < targetWord.length
+              ? targetWord[prev.length]
+              : separator;
+          // Indentation that has to be typed is part of the word
+          if (key === " " && prev.length < targetWord.length && expected === " ") {
+            reportKeystroke(key, expected, true);
+            return prev + key;
+          }
+          const ok = prev === targetWord && key === separator;
+          reportKeystroke(key, expected, ok);
+          if (!ok) {
             // Word doesn't match — don't advance
             return prev;
           }
@@ -470,11 +524,14 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
           setCurrentWordIndex(nextIndex);
 
           // Compute position in full text
-          let pos = 0;
-          for (let w = 0; w <= currentWordIndex; w++) {
-            pos += currentWords[w].length + 1;
-          }
-          onProgressRef.current?.({ pos, wpm: curWpm });
+          const pos = nextStart(currentStarts, currentWordIndex);
+          // Submit the completed word so the server can verify it
+          onProgressRef.current?.({
+            pos,
+            wpm: curWpm,
+            wordIndex: currentWordIndex,
+            word: targetWord,
+          });
 
           return ""; // clear input for next word
         });
@@ -491,6 +548,9 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
         if (prev.length >= targetWord.length + 8) return prev;
 
         const newInput = prev + e.key;
+        const expected =
+          prev.length < targetWord.length ? targetWord[prev.length] : " ";
+        reportKeystroke(e.key, expected, e.key === expected);
 
         // Check if this is the LAST word and it's now fully & correctly typed
         if (
@@ -509,7 +569,12 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
           // Send a final progress update so other players' ghost cursors
           // move all the way to the end (avoids the "2 chars short" bug
           // caused by throttling dropping the last update).
-          onProgressRef.current?.({ pos: text.length, wpm: curWpm });
+          onProgressRef.current?.({
+            pos: text.length,
+            wpm: curWpm,
+            wordIndex: currentWordIndex,
+            word: targetWord,
+          });
           onFinishRef.current?.({ wpm: curWpm, elapsed });
         } else {
           // Update WPM while typing
@@ -527,11 +592,7 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
           }
 
           // Send progress
-          let pos = 0;
-          for (let w = 0; w < currentWordIndex; w++) {
-            pos += currentWords[w].length + 1;
-          }
-          pos += newInput.length;
+          const pos = currentStarts[currentWordIndex] + newInput.length;
           const elapsed2 = startTimeRef.current
             ? Date.now() - startTimeRef.current
             : 0;
@@ -561,6 +622,7 @@ export function useGameLogic({ text, onProgress, onFinish, forcedStartTime }) {
   return {
     charStates,
     cursorPos,
+    wordStarts,
     currentWordIndex,
     currentInput,
     words,
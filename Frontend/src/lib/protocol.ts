@@ -0,0 +1,281 @@
+// Code generated by cmd/protogen from the Go message types. DO NOT EDIT.
+
+/** Version of the websocket protocol these types describe, sent as ?protocol= */
+export const PROTOCOL_VERSION = 2;
+
+/** Type of every message of the protocol */
+export const MessageType = {
+  /** Chat message to everybody in the room */
+  Broadcast: "broadcast",
+  /** Chat message to the user in reciever */
+  Private: "private",
+  /** Notice about the room, e.g. a user joined */
+  String: "string",
+  /** Players of the room with their status */
+  PlayerList: "player_list",
+  /** Player readiness, "ready" or "not ready" */
+  ReadyToggle: "ready_toggle",
+  /** Typed word or keys, answered with the verified progress of the player */
+  PlayerProgress: "player_progress",
+  /** A player finished; clients send the result they claim */
+  GameFinished: "game_finished",
+  /** Host asks to start the round, the server answers with the text of the round */
+  GameStart: "game_start",
+  /** Asks for a fresh player_list */
+  RequestPlayerList: "request_player_list",
+  /** Sets the sender back to not ready */
+  ResetReady: "reset_ready",
+  /** The client arrived on the game page */
+  PlayerJoinedGame: "player_joined_game",
+  /** The host started the round, the content is the seconds to count down before the game page */
+  GameCountdown: "game_countdown",
+  /** The race starts at start_time */
+  GameGo: "game_go",
+  /** Final standings once the race is over */
+  RaceResults: "race_results",
+  /** Batch of key presses, optional telemetry */
+  Keystrokes: "keystrokes",
+  /** Where the text of the round is from: a quote or a code snippet */
+  TextSource: "text_source",
+  /** A player is out of the elimination match */
+  PlayerEliminated: "player_eliminated",
+  /** The last player standing won the elimination match */
+  EliminationWinner: "elimination_winner",
+  /** Resumable session of the connection and the negotiated protocol version */
+  Session: "session",
+  /** A request was refused, the content is the reason */
+  Error: "error",
+  /** Host changes the settings, the server sends the current ones */
+  RoomSettings: "room_settings",
+  /** The host role moved to another player */
+  HostChanged: "host_changed",
+  /** Spectators watching the room */
+  SpectatorList: "spectator_list",
+  /** Host closes the room for everyone */
+  CloseRoom: "close_room",
+  /** Host moves a player to a team */
+  TeamAssign: "team_assign",
+  /** Host has the teams balanced by rating */
+  TeamBalance: "team_balance",
+  /** The room was closed */
+  RoomClosed: "room_closed",
+} as const;
+
+export type MessageType = (typeof MessageType)[keyof typeof MessageType];
+
+export interface PlayerInfo {
+  id: string;
+  name: string;
+  ready: boolean;
+  status: string;
+  host: boolean;
+  team: number;
+}
+
+export interface Input {
+  word_index?: number;
+  word?: string;
+  keys?: string;
+  pos?: number;
+}
+
+export interface Progress {
+  id: string;
+  name: string;
+  pos: number;
+  wpm: number;
+  accuracy: number;
+  finished: boolean;
+  place?: number;
+  time_ms?: number;
+  chars: number;
+}
+
+export interface FinishPayload {
+  wpm: number;
+}
+
+export interface GoPayload {
+  start_time: number;
+  end_time?: number;
+}
+
+export interface ResultsPayload {
+  round: number;
+  reason: string;
+  results: RaceResult[];
+  teams?: TeamResult[];
+}
+
+export interface RaceResult {
+  id: string;
+  name: string;
+  place: number;
+  wpm: number;
+  accuracy: number;
+  time_ms?: number;
+  pos: number;
+  chars: number;
+  dnf: boolean;
+  flagged: boolean;
+  team?: number;
+}
+
+export interface TeamResult {
+  team: number;
+  place: number;
+  score: number;
+  members: string[];
+}
+
+export interface Batch {
+  events: Keystroke[];
+}
+
+export interface Keystroke {
+  k: string;
+  e: string;
+  t: number;
+  ok: boolean;
+}
+
+export interface Quote {
+  id: string;
+  text: string;
+  source: string;
+  author: string;
+  language: string;
+  length: string;
+}
+
+export interface CodeSourcePayload {
+  id: string;
+  language: string;
+  text: string;
+  lines: number;
+  indentation: string;
+}
+
+export interface EliminatedPayload {
+  id: string;
+  name: string;
+  round: number;
+  place: number;
+  remaining: number;
+}
+
+export interface WinnerPayload {
+  id: string;
+  name: string;
+  rounds: number;
+}
+
+export interface SessionPayload {
+  token: string;
+  last_seq: number;
+  resumed: boolean;
+  replayed: number;
+  complete: boolean;
+  protocol: number;
+}
+
+export interface RoomSettings {
+  max_players: number;
+  max_spectators: number;
+  word_count: number;
+  mode: string;
+  language: string;
+  layout: string;
+  privacy: string;
+  countdown: number;
+  time_limit: number;
+  duration: number;
+  teams: number;
+  team_scoring: string;
+  quote_length: string;
+  custom_text?: string;
+  code_language: string;
+  indentation: string;
+  ghost?: GhostSettings;
+}
+
+export interface GhostSettings {
+  user_id?: string;
+  race_id?: string;
+  name?: string;
+}
+
+export interface HostPayload {
+  host_id: string;
+  host_name: string;
+}
+
+export interface SpectatorInfo {
+  id: string;
+  name: string;
+}
+
+export interface TeamAssignPayload {
+  player_id: string;
+  team: number;
+}
+
+export interface ClosedPayload {
+  reason: string;
+  message: string;
+}
+
+/** Fields every message may carry besides type and content */
+export interface Envelope {
+  room_id: string;
+  sender: string;
+  sender_name: string;
+  reciever: string;
+  timestamp: string;
+  seq?: number;
+}
+
+/** A message sent by a client, the envelope is filled in by the server */
+export type ClientMessage =
+  | (Partial<Envelope> & { type: "broadcast"; content: string })
+  | (Partial<Envelope> & { type: "private"; content: string })
+  | (Partial<Envelope> & { type: "ready_toggle"; content: string })
+  | (Partial<Envelope> & { type: "player_progress"; content: Input })
+  | (Partial<Envelope> & { type: "game_finished"; content: FinishPayload })
+  | (Partial<Envelope> & { type: "game_start"; content?: unknown })
+  | (Partial<Envelope> & { type: "request_player_list"; content?: unknown })
+  | (Partial<Envelope> & { type: "reset_ready"; content?: unknown })
+  | (Partial<Envelope> & { type: "player_joined_game"; content?: unknown })
+  | (Partial<Envelope> & { type: "keystrokes"; content: Batch })
+  | (Partial<Envelope> & { type: "room_settings"; content: RoomSettings })
+  | (Partial<Envelope> & { type: "close_room"; content?: unknown })
+  | (Partial<Envelope> & { type: "team_assign"; content: TeamAssignPayload })
+  | (Partial<Envelope> & { type: "team_balance"; content?: unknown });
+
+/** A message sent by the server */
+export type ServerMessage =
+  | (Envelope & { type: "broadcast"; content: string })
+  | (Envelope & { type: "private"; content: string })
+  | (Envelope & { type: "string"; content: string })
+  | (Envelope & { type: "player_list"; content: PlayerInfo[] })
+  | (Envelope & { type: "player_progress"; content: Progress })
+  | (Envelope & { type: "game_finished"; content: Progress })
+  | (Envelope & { type: "game_start"; content: string })
+  | (Envelope & { type: "game_countdown"; content: number })
+  | (Envelope & { type: "game_go"; content: GoPayload })
+  | (Envelope & { type: "race_results"; content: ResultsPayload })
+  | (Envelope & { type: "text_source"; content: Quote | CodeSourcePayload })
+  | (Envelope & { type: "player_eliminated"; content: EliminatedPayload })
+  | (Envelope & { type: "elimination_winner"; content: WinnerPayload })
+  | (Envelope & { type: "session"; content: SessionPayload })
+  | (Envelope & { type: "error"; content: string })
+  | (Envelope & { type: "room_settings"; content: RoomSettings })
+  | (Envelope & { type: "host_changed"; content: HostPayload })
+  | (Envelope & { type: "spectator_list"; content: SpectatorInfo[] })
+  | (Envelope & { type: "room_closed"; content: ClosedPayload });
+
+/** Content of the messages of type T sent by a client */
+export type ClientContent<T extends ClientMessage["type"]> = Extract<ClientMessage, { type: T }>["content"];
+
+/** Content of the messages of type T sent by the server */
+export type ServerContent<T extends ServerMessage["type"]> = Extract<ServerMessage, { type: T }>["content"];
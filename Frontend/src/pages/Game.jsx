@@ -7,6 +7,7 @@ import {
   generateTextSeeded,
 } from "../lib/gameLogic";
 import { useOptionalRoomSocket } from "../context/RoomSocketContext";
+import { MessageType } from "../lib/protocol";
 
 // Colors assigned to other players' ghost cursors
 const GHOST_COLORS = [
@@ -21,6 +22,15 @@ const GHOST_COLORS = [
 // How often (ms) to push progress to the server
 const PROGRESS_THROTTLE_MS = 250;
 
+// Keystroke telemetry (typing analytics) is batched; a batch is sent when it
+// is full or every KEYSTROKE_FLUSH_MS. Players can opt out with
+// localStorage.setItem("gotype:telemetry", "off").
+const KEYSTROKE_BATCH_SIZE = 40;
+const KEYSTROKE_FLUSH_MS = 1000;
+const telemetryEnabled = () =>
+  typeof localStorage === "undefined" ||
+  localStorage.getItem("gotype:telemetry") !== "off";
+
 // ---------------------------------------------------------------------------
 // Main Game component
 // ---------------------------------------------------------------------------
@@ -41,12 +51,25 @@ export default function Game({ mode = "single" }) {
   );
   // Server-authoritative start time (unix ms) — shared across all players
   const [sharedStartTime, setSharedStartTime] = useState(null);
+  // Deadline of a timed round (unix ms), null for word races
+  const [sharedEndTime, setSharedEndTime] = useState(null);
 
   // { [playerName]: { pos, wpm, color } }
   const [otherPlayers, setOtherPlayers] = useState({});
   // Ordered list of all finishers (self + others) in the order they finished.
   // [{ name, wpm, isMe }]
   const [raceResults, setRaceResults] = useState([]);
+  // Set once the server sent the final standings (everyone finished or the
+  // time limit expired) — the results overlay shows even if we didn't finish.
+  const [raceOver, setRaceOver] = useState(false);
+  // Team standings of a team race: [{ team, place, score }]
+  const [teamResults, setTeamResults] = useState([]);
+  // Attribution of a quote round: { source, author }
+  const [textSource, setTextSource] = useState(null);
+  // Elimination matches: knocked out players only watch, the overlay tells
+  // who was eliminated or who won
+  const [eliminated, setEliminated] = useState(false);
+  const [eliminationNotice, setEliminationNotice] = useState(null);
 
   const containerRef = useRef(null);
   const throttleTimer = useRef(null);
@@ -70,31 +93,80 @@ export default function Game({ mode = "single" }) {
     [mode, roomSocket],
   );
 
+  // ---- keystroke telemetry (multiplayer, batched) ----
+  const keystrokesRef = useRef([]);
+  const flushKeystrokes = useCallback(() => {
+    if (keystrokesRef.current.length === 0) return;
+    const events = keystrokesRef.current;
+    keystrokesRef.current = [];
+    sendToRoom({
+      type: MessageType.Keystrokes,
+      room_id: roomId,
+      content: { events },
+    });
+  }, [roomId, sendToRoom]);
+
+  const handleKeystroke = useCallback(
+    (event) => {
+      keystrokesRef.current.push(event);
+      if (keystrokesRef.current.length >= KEYSTROKE_BATCH_SIZE) {
+        flushKeystrokes();
+      }
+    },
+    [flushKeystrokes],
+  );
+
+  useEffect(() => {
+    if (mode !== "multi") return;
+    const id = setInterval(flushKeystrokes, KEYSTROKE_FLUSH_MS);
+    return () => {
+      clearInterval(id);
+      flushKeystrokes();
+    };
+  }, [mode, flushKeystrokes]);
+
   // ---- progress callback (throttled) ----
   const handleProgress = useCallback(
     (progress) => {
       if (mode !== "multi") return;
+      // Completed words are what the server verifies, never throttle them
+      if (progress.word !== undefined) {
+        // The last word ends the race on the server, the telemetry of the
+        // race has to arrive before it
+        if (progress.pos >= text.length) flushKeystrokes();
+        sendToRoom({
+          type: MessageType.PlayerProgress,
+          room_id: roomId,
+          content: {
+            word_index: progress.wordIndex,
+            word: progress.word,
+            pos: progress.pos,
+          },
+        });
+        return;
+      }
       if (throttleTimer.current) return;
       throttleTimer.current = setTimeout(() => {
         throttleTimer.current = null;
         sendToRoom({
-          type: "player_progress",
+          type: MessageType.PlayerProgress,
           room_id: roomId,
-          content: JSON.stringify({ pos: progress.pos, wpm: progress.wpm }),
+          content: { pos: progress.pos },
         });
       }, PROGRESS_THROTTLE_MS);
     },
-    [mode, roomId, sendToRoom],
+    [mode, roomId, sendToRoom, text, flushKeystrokes],
   );
 
   // ---- finish callback ----
   const handleFinish = useCallback(
     (result) => {
       if (mode === "multi") {
+        flushKeystrokes();
         sendToRoom({
-          type: "game_finished",
+          type: MessageType.GameFinished,
           room_id: roomId,
-          content: JSON.stringify({ wpm: result.wpm }),
+          content: { wpm: result.wpm },
         });
         // Add ourselves to the results list — everyone already in the
         // list finished before us, so appending preserves finish order.
@@ -104,13 +176,14 @@ export default function Game({ mode = "single" }) {
         ]);
       }
     },
-    [mode, roomId, sendToRoom],
+    [mode, roomId, sendToRoom, flushKeystrokes],
   );
 
   // ---- game hook ----
   const {
     charStates,
     cursorPos,
+    wordStarts,
     currentWordIndex,
     currentInput,
     words,
@@ -124,8 +197,12 @@ export default function Game({ mode = "single" }) {
     text,
     onProgress: handleProgress,
     onFinish: handleFinish,
+    onKeystroke:
+      mode === "multi" && telemetryEnabled() ? handleKeystroke : undefined,
     // In multiplayer, use the server's start time so all players share one clock
     forcedStartTime: mode === "multi" ? sharedStartTime : undefined,
+    // Code rounds may require the indentation to be typed
+    autoIndent: textSource?.indentation !== "typed",
   });
 
   // Block keyboard input during the pre-game countdown (multiplayer)
@@ -136,9 +213,30 @@ export default function Game({ mode = "single" }) {
         e.preventDefault();
         return;
       }
+      // Spectators only watch the other players' cursors
+      if (roomSocket?.isSpectator) {
+        e.preventDefault();
+        return;
+      }
+      // Timed round is over, the server no longer accepts progress
+      if (sharedEndTime && Date.now() >= sharedEndTime) {
+        e.preventDefault();
+        return;
+      }
+      if (eliminated) {
+        e.preventDefault();
+        return;
+      }
       rawHandleKeyDown(e);
     },
-    [mode, preGameCountdown, rawHandleKeyDown],
+    [
+      mode,
+      preGameCountdown,
+      rawHandleKeyDown,
+      roomSocket,
+      sharedEndTime,
+      eliminated,
+    ],
   );
 
   // ---- singleplayer bootstrap ----
@@ -178,7 +276,7 @@ export default function Game({ mode = "single" }) {
         socketReady: roomSocket.isConnected,
       });
       roomSocket.send({
-        type: "player_joined_game",
+        type: MessageType.PlayerJoinedGame,
         room_id: roomId,
         content: "joined",
       });
@@ -199,16 +297,14 @@ export default function Game({ mode = "single" }) {
     const unsubscribe = roomSocket.subscribe((data) => {
       console.log("[Game] WS message received:", data.type);
       switch (data.type) {
-        case "game_go": {
+        case MessageType.GameGo: {
           // Server sends a single game_go with start_time set ~3 s in the
           // future.  We run the 3→2→1 countdown locally so there's only ONE
           // critical message to deliver (no more dropped countdown ticks).
           try {
-            const payload =
-              typeof data.content === "string"
-                ? JSON.parse(data.content)
-                : data.content;
+            const payload = data.content;
             const startTime = payload.start_time; // unix ms
+            setSharedEndTime(payload.end_time ?? null); // timed rounds only
             console.log("[Game] game_go received!", {
               startTime,
               now: Date.now(),
@@ -247,7 +343,18 @@ export default function Game({ mode = "single" }) {
           }
           break;
         }
-        case "game_start": {
+        case MessageType.GameStart: {
+          // Elimination rounds follow each other on this page: clear the
+          // previous round before the next countdown
+          setRaceOver(false);
+          setRaceResults([]);
+          setTeamResults([]);
+          setTextSource(null);
+          setOtherPlayers({});
+          setSharedStartTime(null);
+          setSharedEndTime(null);
+          setPreGameCountdown(3);
+          setEliminationNotice(null);
           try {
             const gameText = JSON.parse(data.content);
             setText(gameText);
@@ -260,13 +367,15 @@ export default function Game({ mode = "single" }) {
           }
           break;
         }
-        case "player_progress": {
+        case MessageType.PlayerProgress: {
           if (!data.sender || data.sender === myNameRef.current) break;
           try {
-            const prog = JSON.parse(data.content);
+            const prog = data.content;
+            // Keyed by the sender's user ID, names are only for display
             setOtherPlayers((prev) => ({
               ...prev,
               [data.sender]: {
+                name: data.sender_name || data.sender,
                 pos: prog.pos ?? 0,
                 wpm: prog.wpm ?? 0,
                 color:
@@ -279,14 +388,18 @@ export default function Game({ mode = "single" }) {
           }
           break;
         }
-        case "game_finished": {
+        case MessageType.GameFinished: {
           if (!data.sender || data.sender === myNameRef.current) break;
           try {
-            const result = JSON.parse(data.content);
+            const result = data.content;
             // Append to raceResults — order of arrival = finish order
             setRaceResults((prev) => [
               ...prev,
-              { name: data.sender, wpm: result.wpm, isMe: false },
+              {
+                name: data.sender_name || data.sender,
+                wpm: result.wpm,
+                isMe: false,
+              },
             ]);
             // Move the ghost cursor to the very end of the text
             setOtherPlayers((prev) => {
@@ -305,6 +418,62 @@ export default function Game({ mode = "single" }) {
           }
           break;
         }
+        case MessageType.RaceResults: {
+          try {
+            const payload = data.content;
+            const myId = roomSocket.me?.id;
+            // Authoritative standings replace the locally collected ones
+            setRaceResults(
+              payload.results.map((r) => ({
+                name: r.name,
+                wpm: r.wpm,
+                team: r.team,
+                dnf: r.dnf,
+                flagged: r.flagged,
+                isMe: r.id === myId,
+              })),
+            );
+            setTeamResults(payload.teams || []);
+            setRaceOver(true);
+          } catch {
+            /* ignore */
+          }
+          break;
+        }
+        case MessageType.TextSource: {
+          try {
+            setTextSource(data.content);
+          } catch {
+            /* ignore */
+          }
+          break;
+        }
+        case MessageType.PlayerEliminated: {
+          try {
+            const info = data.content;
+            const isMe = info.id === roomSocket.me?.id;
+            if (isMe) setEliminated(true);
+            setEliminationNotice(
+              `${isMe ? "You were" : `${info.name} was`} eliminated in round ${info.round} — ${info.remaining} left`,
+            );
+          } catch {
+            /* ignore */
+          }
+          break;
+        }
+        case MessageType.EliminationWinner: {
+          try {
+            const winner = data.content;
+            const isMe = winner.id === roomSocket.me?.id;
+            setEliminated(false);
+            setEliminationNotice(
+              `${isMe ? "You win" : `${winner.name} wins`} the match after ${winner.rounds} rounds!`,
+            );
+          } catch {
+            /* ignore */
+          }
+          break;
+        }
         default:
           break;
       }
@@ -349,16 +518,21 @@ export default function Game({ mode = "single" }) {
   // ---- time display ----
   const totalSec = Math.floor(timeElapsed / 1000);
   const timeStr = `${totalSec}`;
+  // Timed rounds count down to the server deadline instead of up
+  const clockStr =
+    sharedEndTime && sharedStartTime
+      ? `${Math.max(0, Math.ceil((sharedEndTime - sharedStartTime - timeElapsed) / 1000))}`
+      : timeStr;
 
   // ---------------------------------------------------------------------------
   // Build word-level rendering data
   // ---------------------------------------------------------------------------
   const wordRenderData = [];
   {
-    let charOffset = 0;
     for (let w = 0; w < words.length; w++) {
       const word = words[w];
-      const wordStart = charOffset;
+      const wordStart = wordStarts[w];
+      let charOffset = wordStart;
       const chars = [];
 
       for (let c = 0; c < word.length; c++) {
@@ -381,11 +555,13 @@ export default function Game({ mode = "single" }) {
         wordEnd: charOffset - 1,
         chars,
         extraChars,
+        // Whitespace up to the next word: a space, or a newline and the
+        // indentation in code rounds
+        separator: text.slice(
+          charOffset,
+          w < words.length - 1 ? wordStarts[w + 1] : charOffset,
+        ),
       });
-
-      if (w < words.length - 1) {
-        charOffset++; // space
-      }
     }
   }
 
@@ -443,7 +619,7 @@ export default function Game({ mode = "single" }) {
           {/* Timer */}
           <div>
             <span className="text-4xl font-bold text-foreground">
-              {timeStr}
+              {clockStr}
             </span>
           </div>
           {/* My WPM */}
@@ -453,8 +629,8 @@ export default function Game({ mode = "single" }) {
           </div>
           {/* Other players' WPM (multiplayer only) */}
           {mode === "multi" &&
-            Object.entries(otherPlayers).map(([name, player]) => (
-              <div key={name} className="flex flex-col">
+            Object.entries(otherPlayers).map(([id, player]) => (
+              <div key={id} className="flex flex-col">
                 <span
                   className="text-2xl font-bold"
                   style={{ color: player.color }}
@@ -465,7 +641,7 @@ export default function Game({ mode = "single" }) {
                   className="text-xs truncate max-w-[80px]"
                   style={{ color: player.color, opacity: 0.7 }}
                 >
-                  {name}
+                  {player.name}
                 </span>
               </div>
             ))}
@@ -516,7 +692,7 @@ export default function Game({ mode = "single" }) {
                   <span
                     style={{
                       display: "inline-block",
-                      whiteSpace: "nowrap",
+                      whiteSpace: "pre",
                     }}
                   >
                     {wd.chars.map((ch, ci) => {
@@ -528,9 +704,9 @@ export default function Game({ mode = "single" }) {
                       return (
                         <Fragment key={ci}>
                           {/* Ghost cursors */}
-                          {ghostsHere.map(([name, player]) => (
+                          {ghostsHere.map(([id, player]) => (
                             <span
-                              key={`ghost-${name}`}
+                              key={`ghost-${id}`}
                               className="relative"
                               style={{
                                 display: "inline-block",
@@ -561,7 +737,7 @@ export default function Game({ mode = "single" }) {
                                   fontWeight: 500,
                                 }}
                               >
-                                {name}
+                                {player.name}
                               </span>
                             </span>
                           ))}
@@ -636,9 +812,9 @@ export default function Game({ mode = "single" }) {
                         .filter(
                           ([, p]) => p.pos === wd.wordStart + wd.chars.length,
                         )
-                        .map(([name, player]) => (
+                        .map(([id, player]) => (
                           <span
-                            key={`ghost-end-${name}`}
+                            key={`ghost-end-${id}`}
                             className="relative"
                             style={{
                               display: "inline-block",
@@ -669,22 +845,27 @@ export default function Game({ mode = "single" }) {
                                 fontWeight: 500,
                               }}
                             >
-                              {name}
+                              {player.name}
                             </span>
                           </span>
                         ))}
                   </span>
 
-                  {/* Space between words */}
-                  {wi < wordRenderData.length - 1 && (
+                  {/* Whitespace between words, newlines kept */}
+                  {wd.separator && (
                     <span
                       className={
                         wd.wordIndex < currentWordIndex
                           ? "text-foreground"
                           : "text-muted-foreground/40"
                       }
+                      style={
+                        wd.separator === " "
+                          ? undefined
+                          : { whiteSpace: "pre" }
+                      }
                     >
-                      {" "}
+                      {wd.separator}
                     </span>
                   )}
                 </Fragment>
@@ -730,16 +911,25 @@ export default function Game({ mode = "single" }) {
           </div>
         )}
 
+        {/* ---- Quote attribution ---- */}
+        {textSource && (
+          <div className="self-end mt-4 text-sm text-muted-foreground italic">
+            {textSource.author
+              ? `— ${textSource.author}, ${textSource.source}`
+              : `${textSource.language} snippet`}
+          </div>
+        )}
+
         {/* ---- Multiplayer progress bars ---- */}
         {mode === "multi" && !isFinished && (
           <div className="w-full mt-8 space-y-3">
             {/* My progress */}
             <ProgressBar label={myNameRef.current} pct={myPct} wpm={wpm} isMe />
             {/* Other players */}
-            {Object.entries(otherPlayers).map(([name, player]) => (
+            {Object.entries(otherPlayers).map(([id, player]) => (
               <ProgressBar
-                key={name}
-                label={name}
+                key={id}
+                label={player.name}
                 pct={text ? Math.round((player.pos / text.length) * 100) : 0}
                 wpm={player.wpm}
                 color={player.color}
@@ -750,7 +940,7 @@ export default function Game({ mode = "single" }) {
       </div>
 
       {/* ---- Results overlay ---- */}
-      {isFinished && (
+      {(isFinished || raceOver) && (
         <div className="fixed inset-0 z-50 bg-background flex items-center justify-center font-mono">
           <div className="text-center">
             <div className="text-7xl font-bold text-foreground leading-none">
@@ -776,11 +966,33 @@ export default function Game({ mode = "single" }) {
                     className={`text-sm mb-1 ${r.isMe ? "text-foreground font-semibold" : "text-muted-foreground"}`}
                   >
                     #{i + 1} {r.isMe ? "You" : r.name} — {r.wpm} wpm
+                    {r.team > 0 && ` (team ${r.team})`}
+                    {r.dnf && " (DNF)"}
+                    {r.flagged && " (flagged)"}
+                  </div>
+                ))}
+              </div>
+            )}
+
+            {mode === "multi" && teamResults.length > 0 && (
+              <div className="mt-7">
+                <div className="text-muted-foreground text-xs mb-2 uppercase tracking-widest">
+                  Teams
+                </div>
+                {teamResults.map((t) => (
+                  <div key={t.team} className="text-sm mb-1 text-foreground">
+                    #{t.place} Team {t.team} — {t.score} wpm
                   </div>
                 ))}
               </div>
             )}
 
+            {mode === "multi" && eliminationNotice && (
+              <div className="mt-5 text-foreground text-sm font-semibold">
+                {eliminationNotice}
+              </div>
+            )}
+
             <div className="flex gap-4 justify-center mt-9">
               {mode === "single" ? (
                 <>
@@ -4,15 +4,26 @@ import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { useRoomSocket } from "../context/RoomSocketContext";
 import { generateTextSeeded } from "../lib/gameLogic";
+import { MessageType } from "../lib/protocol";
 
 export default function Lobby() {
-  const { roomId, send, subscribe, connectionStatus, isConnected } =
-    useRoomSocket();
+  const {
+    roomId,
+    me,
+    isSpectator,
+    send,
+    subscribe,
+    connectionStatus,
+    isConnected,
+  } = useRoomSocket();
   const navigate = useNavigate();
 
   const chatEndRef = useRef(null);
+  // Number of teams from the room settings, 0 when everyone races alone
+  const [teamCount, setTeamCount] = useState(0);
 
   const [players, setPlayers] = useState([]);
+  const [spectators, setSpectators] = useState([]);
   const [messages, setMessages] = useState([]);
   const [messageInput, setMessageInput] = useState("");
   const [isReady, setIsReady] = useState(false);
@@ -30,14 +41,14 @@ export default function Lobby() {
 
     // Reset our own ready state on the server
     send({
-      type: "reset_ready",
+      type: MessageType.ResetReady,
       room_id: roomId,
       content: "reset",
     });
 
     // Ask the server to broadcast the current player list
     send({
-      type: "request_player_list",
+      type: MessageType.RequestPlayerList,
       room_id: roomId,
       content: "request",
     });
@@ -54,12 +65,46 @@ export default function Lobby() {
     };
   }, []);
 
+  // Counts down the seconds the server sent when the host started the
+  // round, then moves to the game page
+  const startCountdown = useCallback(
+    (seconds) => {
+      if (countdownRef.current) clearInterval(countdownRef.current);
+      setCountdown(seconds);
+      countdownRef.current = setInterval(() => {
+        setCountdown((prev) => {
+          if (prev === null) return null;
+          if (prev <= 1) {
+            clearInterval(countdownRef.current);
+            countdownRef.current = null;
+            const gameText = generateTextSeeded(roomId, 25);
+            // Navigate within the same /room/:roomId layout — WebSocket stays alive
+            navigate(`/room/${roomId}/game`, { state: { text: gameText } });
+            return 0;
+          }
+          return prev - 1;
+        });
+      }, 1000);
+    },
+    [navigate, roomId],
+  );
+
+  // Stop the countdown when leaving the lobby
+  useEffect(() => {
+    return () => {
+      if (countdownRef.current) {
+        clearInterval(countdownRef.current);
+        countdownRef.current = null;
+      }
+    };
+  }, []);
+
   // Subscribe to incoming WebSocket messages
   useEffect(() => {
     const unsubscribe = subscribe((data) => {
-      if (data.type === "player_list") {
+      if (data.type === MessageType.PlayerList) {
         try {
-          const playerList = JSON.parse(data.content);
+          const playerList = data.content;
           setPlayers(playerList);
 
           // When we receive the authoritative player list, sync our local
@@ -73,16 +118,37 @@ export default function Lobby() {
         } catch (e) {
           console.error("Invalid player_list JSON:", data.content);
         }
-      } else if (data.type === "broadcast") {
+      } else if (data.type === MessageType.RoomSettings) {
+        try {
+          setTeamCount(data.content.teams || 0);
+        } catch (e) {
+          console.error("Invalid room_settings JSON:", data.content);
+        }
+      } else if (data.type === MessageType.SpectatorList) {
+        try {
+          setSpectators(data.content);
+        } catch (e) {
+          console.error("Invalid spectator_list JSON:", data.content);
+        }
+      } else if (data.type === MessageType.GameCountdown) {
+        // Only the host starts a round, the server tells everyone
+        startCountdown(Number(data.content) || 3);
+      } else if (data.type === MessageType.Error) {
+        // The server refused a request (e.g. only the host can start)
+        setMessages((prev) => [
+          ...prev,
+          { sender: "System", content: data.content, timestamp: data.timestamp },
+        ]);
+      } else if (data.type === MessageType.Broadcast) {
         setMessages((prev) => [
           ...prev,
           {
-            sender: data.sender,
+            sender: data.sender_name || data.sender,
             content: data.content,
             timestamp: data.timestamp,
           },
         ]);
-      } else if (data.type === "string") {
+      } else if (data.type === MessageType.String) {
         try {
           const content = JSON.parse(data.content);
           setMessages((prev) => [
@@ -100,7 +166,7 @@ export default function Lobby() {
     });
 
     return unsubscribe;
-  }, [subscribe]);
+  }, [subscribe, startCountdown]);
 
   // Auto-scroll chat
   useEffect(() => {
@@ -119,7 +185,7 @@ export default function Lobby() {
     }
 
     send({
-      type: "broadcast",
+      type: MessageType.Broadcast,
       content: content,
       room_id: roomId,
     });
@@ -150,7 +216,7 @@ export default function Lobby() {
 
     // Send the DESIRED state — the server will SET it, not toggle
     send({
-      type: "ready_toggle",
+      type: MessageType.ReadyToggle,
       room_id: roomId,
       content: newState ? "ready" : "not ready",
     });
@@ -160,6 +226,38 @@ export default function Lobby() {
     setTimeout(() => setReadyInFlight(false), 2000);
   }, [isConnected, readyInFlight, isReady, send, roomId]);
 
+  // Host only: ask the server to start the round (marks everyone ready)
+  const startGame = useCallback(() => {
+    if (!isConnected) return;
+    send({
+      type: MessageType.GameStart,
+      room_id: roomId,
+      content: "start",
+    });
+  }, [isConnected, send, roomId]);
+
+  // Host only: move a player to the next team
+  const cycleTeam = useCallback(
+    (player) => {
+      if (!isConnected || teamCount === 0) return;
+      send({
+        type: MessageType.TeamAssign,
+        room_id: roomId,
+        content: {
+          player_id: player.id,
+          team: (player.team % teamCount) + 1,
+        },
+      });
+    },
+    [isConnected, teamCount, send, roomId],
+  );
+
+  // Host only: let the server balance the teams by rating
+  const balanceTeams = useCallback(() => {
+    if (!isConnected) return;
+    send({ type: MessageType.TeamBalance, room_id: roomId, content: "balance" });
+  }, [isConnected, send, roomId]);
+
   const handleLeaveRoom = useCallback(() => {
     console.log("👋 Leaving room");
     // Navigation away from /room/:roomId/* will unmount RoomLayout,
@@ -169,43 +267,9 @@ export default function Lobby() {
 
   const allReady =
     players.length > 0 && players.every((p) => p.status === "ready");
+  const isHost = players.some((p) => p.host && p.id === me?.id);
   const someInGame = players.some((p) => p.status === "in_game");
 
-  // Auto-start: countdown when all players are ready
-  useEffect(() => {
-    if (allReady && isConnected) {
-      setCountdown(3);
-      countdownRef.current = setInterval(() => {
-        setCountdown((prev) => {
-          if (prev === null) return null;
-          if (prev <= 1) {
-            clearInterval(countdownRef.current);
-            countdownRef.current = null;
-            const gameText = generateTextSeeded(roomId, 25);
-            // Navigate within the same /room/:roomId layout — WebSocket stays alive
-            navigate(`/room/${roomId}/game`, { state: { text: gameText } });
-            return 0;
-          }
-          return prev - 1;
-        });
-      }, 1000);
-    } else {
-      // Someone un-readied or disconnected — cancel countdown
-      if (countdownRef.current) {
-        clearInterval(countdownRef.current);
-        countdownRef.current = null;
-      }
-      setCountdown(null);
-    }
-
-    return () => {
-      if (countdownRef.current) {
-        clearInterval(countdownRef.current);
-        countdownRef.current = null;
-      }
-    };
-  }, [allReady, isConnected, navigate, roomId]);
-
   return (
     <div className="flex flex-col h-screen bg-gray-50">
       <header className="p-4 flex justify-between items-center bg-white shadow">
@@ -231,7 +295,23 @@ export default function Lobby() {
                   key={i}
                   className="px-3 py-2 bg-gray-100 rounded-md text-gray-700 flex justify-between"
                 >
-                  <span>{p.name}</span>
+                  <span>
+                    {p.name}
+                    {p.host && (
+                      <span className="ml-2 text-xs text-blue-500">host</span>
+                    )}
+                    {teamCount > 0 && p.team > 0 && (
+                      <button
+                        type="button"
+                        onClick={() => isHost && cycleTeam(p)}
+                        disabled={!isHost}
+                        title={isHost ? "Move to the next team" : undefined}
+                        className="ml-2 text-xs text-purple-600"
+                      >
+                        team {p.team}
+                      </button>
+                    )}
+                  </span>
                   {p.status === "ready" && (
                     <span className="text-green-500 font-medium">Ready</span>
                   )}
@@ -245,14 +325,56 @@ export default function Lobby() {
             )}
           </ul>
 
-          <Button
-            onClick={toggleReady}
-            variant={isReady ? "secondary" : "default"}
-            className="mt-4 w-full"
-            disabled={!isConnected || readyInFlight}
-          >
-            {readyInFlight ? "..." : isReady ? "Unready" : "Ready"}
-          </Button>
+          {spectators.length > 0 && (
+            <>
+              <h2 className="text-lg font-semibold mt-4 mb-3">Spectators</h2>
+              <ul className="space-y-2">
+                {spectators.map((s) => (
+                  <li
+                    key={s.id}
+                    className="px-3 py-2 bg-gray-50 rounded-md text-gray-500"
+                  >
+                    {s.name}
+                  </li>
+                ))}
+              </ul>
+            </>
+          )}
+
+          {isSpectator ? (
+            <p className="mt-4 text-sm text-gray-500 text-center">
+              You are spectating this room
+            </p>
+          ) : (
+            <Button
+              onClick={toggleReady}
+              variant={isReady ? "secondary" : "default"}
+              className="mt-4 w-full"
+              disabled={!isConnected || readyInFlight}
+            >
+              {readyInFlight ? "..." : isReady ? "Unready" : "Ready"}
+            </Button>
+          )}
+          {isHost && (
+            <Button
+              onClick={startGame}
+              variant="outline"
+              className="mt-2 w-full"
+              disabled={!isConnected || someInGame}
+            >
+              Start Game
+            </Button>
+          )}
+          {isHost && teamCount > 0 && (
+            <Button
+              onClick={balanceTeams}
+              variant="outline"
+              className="mt-2 w-full"
+              disabled={!isConnected || someInGame}
+            >
+              Balance Teams
+            </Button>
+          )}
         </aside>
 
         {/* Chat Section */}
@@ -305,7 +427,9 @@ export default function Lobby() {
             Starting in {countdown}...
           </span>
         ) : allReady ? (
-          <span className="text-sm text-green-600">All players ready!</span>
+          <span className="text-sm text-green-600">
+            All players ready, waiting for the host to start
+          </span>
         ) : someInGame ? (
           <span className="text-sm text-yellow-600">
             Waiting for players to finish their game...
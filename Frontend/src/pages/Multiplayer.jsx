@@ -2,13 +2,16 @@ import { useState, useEffect } from "react";
 import { useNavigate } from "react-router-dom";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
-import { fetchUser } from "../lib/api";
+import { fetchUser, fetchLanguages } from "../lib/api";
 import { API_URL } from "../lib/config";
 
 export default function Multiplayer() {
   const [roomCode, setRoomCode] = useState("");
   const [user, setUser] = useState(null);
   const [loading, setLoading] = useState(true);
+  const [languages, setLanguages] = useState([]);
+  const [language, setLanguage] = useState("en");
+  const [layout, setLayout] = useState("");
   const navigate = useNavigate();
 
   useEffect(() => {
@@ -26,6 +29,10 @@ export default function Multiplayer() {
       });
   }, [navigate]);
 
+  useEffect(() => {
+    fetchLanguages().then(setLanguages);
+  }, []);
+
   const handleJoinRoom = () => {
     if (!roomCode.trim()) {
       alert("Please enter a valid room code.");
@@ -34,12 +41,21 @@ export default function Multiplayer() {
     navigate(`/room/${roomCode}/lobby`);
   };
 
-  const handleCreateRoom = async () => {
+  // Layouts the selected language is typed on, the default first. An empty
+  // layout lets the server pick the default.
+  const layouts = languages.find((l) => l.code === language)?.layouts || [];
+
+  // settings is optional, e.g. { ghost: { user_id: "me" } } to race against
+  // your own best recorded race. The selected language and layout apply to
+  // every room, a ghost room takes the language of the ghost's race.
+  const handleCreateRoom = async (settings) => {
+    settings = { language, layout, ...settings };
     try {
       const res = await fetch(`${API_URL}/api/create-room`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         credentials: "include",
+        body: JSON.stringify(settings),
       });
 
       if (!res.ok) {
@@ -48,6 +64,11 @@ export default function Multiplayer() {
           navigate("/");
           return;
         }
+        if (res.status === 400) {
+          const data = await res.json().catch(() => ({}));
+          alert(data.error || "Invalid room settings.");
+          return;
+        }
         throw new Error("Failed to create room");
       }
 
@@ -87,9 +108,104 @@ export default function Multiplayer() {
 
       <p className="text-gray-500 my-2">— OR —</p>
 
-      <Button onClick={handleCreateRoom} className="w-64">
+      {languages.length > 1 && (
+        <select
+          value={language}
+          onChange={(e) => {
+            setLanguage(e.target.value);
+            setLayout("");
+          }}
+          className="w-64 border rounded-md px-3 py-2 bg-background"
+        >
+          {languages.map((l) => (
+            <option key={l.code} value={l.code}>
+              {l.name}
+            </option>
+          ))}
+        </select>
+      )}
+      {layouts.length > 1 && (
+        <select
+          value={layout || layouts[0].code}
+          onChange={(e) => setLayout(e.target.value)}
+          className="w-64 border rounded-md px-3 py-2 bg-background"
+        >
+          {layouts.map((l) => (
+            <option key={l.code} value={l.code}>
+              {l.name}
+            </option>
+          ))}
+        </select>
+      )}
+      <Button onClick={() => handleCreateRoom()} className="w-64">
         Create New Room
       </Button>
+      <Button
+        variant="outline"
+        onClick={() => handleCreateRoom({ ghost: { user_id: "me" } })}
+        className="w-64 mt-2"
+      >
+        Race Your Best (Ghost)
+      </Button>
+      <Button
+        variant="outline"
+        onClick={() => handleCreateRoom({ mode: "elimination" })}
+        className="w-64 mt-2"
+      >
+        Elimination Room
+      </Button>
+      <Button
+        variant="outline"
+        onClick={() => handleCreateRoom({ mode: "quote" })}
+        className="w-64 mt-2"
+      >
+        Quote Room
+      </Button>
+      <Button
+        variant="outline"
+        onClick={() => {
+          const customText = window.prompt("Text to race on:");
+          if (customText) {
+            handleCreateRoom({ mode: "custom", custom_text: customText });
+          }
+        }}
+        className="w-64 mt-2"
+      >
+        Custom Text Room
+      </Button>
+      <div className="flex gap-2 mt-2 w-64">
+        {["auto", "typed"].map((indentation) => (
+          <Button
+            key={indentation}
+            variant="outline"
+            className="flex-1"
+            onClick={() => handleCreateRoom({ mode: "code", indentation })}
+          >
+            Code ({indentation} indent)
+          </Button>
+        ))}
+      </div>
+      <Button
+        variant="outline"
+        onClick={() => handleCreateRoom({ teams: 2 })}
+        className="w-64 mt-2"
+      >
+        Team Room (2 Teams)
+      </Button>
+      <div className="flex gap-2 mt-2 w-64">
+        {[15, 30, 60, 120].map((seconds) => (
+          <Button
+            key={seconds}
+            variant="outline"
+            className="flex-1"
+            onClick={() =>
+              handleCreateRoom({ mode: "timed", duration: seconds })
+            }
+          >
+            {seconds}s
+          </Button>
+        ))}
+      </div>
     </div>
   );
 }